@@ -0,0 +1,44 @@
+package tfs
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewDoctorCommand returns a new cobra.Command for the "doctor" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewDoctorCommand(cache *tfs.LocalCache) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Check the tfs installation for common problems",
+		Example: "doctor --json",
+		Args:    cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			diagnosis := cache.Doctor()
+
+			format := viper.GetString("output")
+			if jsonOutput {
+				format = tfs.OutputJSON
+			}
+			if err := tfs.WriteOutput(os.Stdout, format, diagnosis); err != nil {
+				return err
+			}
+
+			if diagnosis.Failed() {
+				return errors.New("some checks failed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the diagnosis as a JSON document (same as --output json)")
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package tfs
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewEnvCommand returns a new cobra.Command for the "env" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewEnvCommand(cache *tfs.LocalCache) *cobra.Command {
+	var shell string
+
+	cmd := &cobra.Command{
+		Use:     "env [version-or-constraint]",
+		Short:   "Print shell code that puts the required Terraform version first in PATH",
+		Example: `eval "$(tfs env)"`,
+		Args:    cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The output is meant to be evaluated on every directory change.
+			logLevel.Set(slog.LevelWarn)
+
+			var expr string
+			if len(args) != 0 {
+				expr = args[0]
+			}
+
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			code, err := cache.Env(expr, shell)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), code)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&shell, "shell", tfs.DefaultShell(), "Shell to print code for (bash, zsh or fish)")
+
+	return cmd
+}
@@ -0,0 +1,43 @@
+package tfs
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewExecCommand returns a new cobra.Command for the "exec" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewExecCommand(cache *tfs.LocalCache) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "exec [version-or-constraint] -- [terraform arguments]",
+		Short:   "Run a Terraform version without changing the active one",
+		Example: "exec '~> 1.6' -- plan -out=plan.tfplan",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Everything after "--" is passed to Terraform.
+			dash := cmd.ArgsLenAtDash()
+			if dash == -1 {
+				dash = len(args)
+			}
+			if dash > 1 {
+				return errors.New("Terraform arguments must follow '--'")
+			}
+
+			var expr string
+			if dash == 1 {
+				expr = args[0]
+			}
+
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			return cache.Exec(expr, args[dash:])
+		},
+	}
+
+	return cmd
+}
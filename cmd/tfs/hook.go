@@ -0,0 +1,42 @@
+package tfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewHookCommand returns a new cobra.Command for the "hook" subcommand.
+func NewHookCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:       "hook <" + strings.Join(tfs.Shells, "|") + ">",
+		Short:     "Print shell code that runs 'tfs env' when the directory changes",
+		Example:   `eval "$(tfs hook bash)"   # in ~/.bashrc`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: tfs.Shells,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			executable, err := os.Executable()
+			if err == nil {
+				executable, err = filepath.EvalSymlinks(executable)
+			}
+			if err != nil {
+				return err
+			}
+
+			code, err := tfs.Hook(args[0], executable)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), code)
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -19,7 +19,12 @@ func NewListCommand(cache *tfs.LocalCache) *cobra.Command {
 				return err
 			}
 
-			return cache.List()
+			list, err := cache.List()
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(list)
 		},
 	}
 }
@@ -0,0 +1,43 @@
+package tfs
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewListRemoteCommand returns a new cobra.Command for the "list-remote" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewListRemoteCommand(cache *tfs.LocalCache) *cobra.Command {
+	var (
+		constraint         string
+		includePrereleases bool
+		limit              int
+	)
+
+	cmd := &cobra.Command{
+		Use:     "list-remote",
+		Short:   "List Terraform versions available for installation",
+		Example: "list-remote --constraint '~> 1.6' --limit 5",
+		Args:    cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			list, err := cache.ListRemote(constraint, includePrereleases, limit)
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(list)
+		},
+	}
+
+	cmd.Flags().StringVarP(&constraint, "constraint", "c", "", "Only list versions satisfying this constraint")
+	cmd.Flags().BoolVar(&includePrereleases, "include-prereleases", false, "Also list alpha, beta and rc versions")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "Only list the N most recent versions")
+
+	return cmd
+}
@@ -0,0 +1,38 @@
+package tfs
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewLockCommand returns a new cobra.Command for the "lock" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewLockCommand(cache *tfs.LocalCache) *cobra.Command {
+	var upgrade bool
+
+	cmd := &cobra.Command{
+		Use:     "lock",
+		Short:   "Pin the Terraform version of the project in a " + tfs.ProjectLockFileName + " file",
+		Example: "lock --upgrade",
+		Args:    cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			req, err := tfs.FindVersionRequirement()
+			if err != nil {
+				return err
+			}
+
+			_, err = cache.LockProject(req, upgrade)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&upgrade, "upgrade", false, "Select the most recent release satisfying the version requirement")
+
+	return cmd
+}
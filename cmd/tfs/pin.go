@@ -0,0 +1,68 @@
+package tfs
+
+import (
+	"log/slog"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewPinCommand returns a new cobra.Command for the "pin" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewPinCommand(cache *tfs.LocalCache) *cobra.Command {
+	return &cobra.Command{
+		Use:     "pin",
+		Short:   "Protect a cached Terraform version from pruning and automatic cleanup",
+		Example: "pin 1.5.7",
+		Args:    versionArg,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ignoring potential errors here because we have already
+			// checked that the argument is a valid semantic version.
+			v, _ := version.NewVersion(args[0])
+
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			return cache.Pin(v)
+		},
+	}
+}
+
+// NewUnpinCommand returns a new cobra.Command for the "unpin" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewUnpinCommand(cache *tfs.LocalCache) *cobra.Command {
+	return &cobra.Command{
+		Use:     "unpin",
+		Short:   "Remove the protection set by 'tfs pin'",
+		Example: "unpin 1.5.7",
+		Args:    versionArg,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, _ := version.NewVersion(args[0])
+
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			return cache.Unpin(v)
+		},
+	}
+}
+
+// versionArg makes sure the command receives exactly one Terraform version.
+func versionArg(cmd *cobra.Command, args []string) error {
+	if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+		slog.Error("This command supports one positional argument exactly")
+		return err
+	}
+	if _, err := version.NewVersion(args[0]); err != nil {
+		slog.Error("Command argument should be a valid Terraform version")
+		return err
+	}
+	return nil
+}
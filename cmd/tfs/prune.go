@@ -8,7 +8,9 @@ import (
 // NewPruneCommand returns a new cobra.Command for the "prune" subcommand.
 // It receives the cache instance that will be used by the command.
 func NewPruneCommand(cache *tfs.LocalCache) *cobra.Command {
-	return &cobra.Command{
+	var includePinned bool
+
+	cmd := &cobra.Command{
 		Use:   "prune",
 		Short: "Remove all Terraform binaries from the local cache",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -16,7 +18,20 @@ func NewPruneCommand(cache *tfs.LocalCache) *cobra.Command {
 			if err := cache.Load(); err != nil {
 				return err
 			}
-			return cache.Prune()
+
+			report, err := cache.Prune(includePinned)
+			if report == nil {
+				return err
+			}
+			if err := writeOutput(report); err != nil {
+				return err
+			}
+
+			return err
 		},
 	}
+
+	cmd.Flags().BoolVar(&includePinned, "include-pinned", false, "Also remove pinned versions")
+
+	return cmd
 }
@@ -11,7 +11,9 @@ import (
 // NewPruneUntilCommand returns a new cobra.Command for the "prune-until" subcommand.
 // It receives the cache instance that will be used by the command.
 func NewPruneUntilCommand(cache *tfs.LocalCache) *cobra.Command {
-	return &cobra.Command{
+	var includePinned bool
+
+	cmd := &cobra.Command{
 		Use:     "prune-until",
 		Short:   "Remove all Terraform binary versions prior to the one specified",
 		Example: "prune-until 1.5.0",
@@ -40,7 +42,19 @@ func NewPruneUntilCommand(cache *tfs.LocalCache) *cobra.Command {
 				return err
 			}
 
-			return cache.PruneUntil(v)
+			report, err := cache.PruneUntil(v, includePinned)
+			if report == nil {
+				return err
+			}
+			if err := writeOutput(report); err != nil {
+				return err
+			}
+
+			return err
 		},
 	}
+
+	cmd.Flags().BoolVar(&includePinned, "include-pinned", false, "Also remove pinned versions")
+
+	return cmd
 }
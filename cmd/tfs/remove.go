@@ -0,0 +1,76 @@
+package tfs
+
+import (
+	"log/slog"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewRemoveCommand returns a new cobra.Command for the "remove" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewRemoveCommand(cache *tfs.LocalCache) *cobra.Command {
+	var (
+		opts        tfs.RemoveOptions
+		unusedSince string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "remove [version...]",
+		Aliases: []string{"rm"},
+		Short:   "Remove Terraform versions from the local cache",
+		Example: "remove 1.5.0 1.5.1\nremove --constraint '>= 1.3, < 1.5'\nremove --prereleases --unused-since 90d",
+
+		Args: func(cmd *cobra.Command, args []string) error {
+			for _, arg := range args {
+				if _, err := version.NewVersion(arg); err != nil {
+					slog.Error("Command arguments should be valid Terraform versions")
+					return err
+				}
+			}
+			return nil
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ignoring potential errors here because we have already
+			// checked that the arguments are valid semantic versions.
+			for _, arg := range args {
+				v, _ := version.NewVersion(arg)
+				opts.Versions = append(opts.Versions, v)
+			}
+
+			if unusedSince != "" {
+				d, err := tfs.ParseAge(unusedSince)
+				if err != nil {
+					slog.Error("Invalid command line flag", "error", err)
+					return err
+				}
+				opts.UnusedSince = d
+			}
+
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			report, err := cache.Remove(opts)
+			if report == nil {
+				return err
+			}
+			if err := writeOutput(report); err != nil {
+				return err
+			}
+
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Constraint, "constraint", "c", "", "Only remove versions satisfying this constraint")
+	cmd.Flags().BoolVar(&opts.Prereleases, "prereleases", false, "Only remove alpha, beta and rc versions")
+	cmd.Flags().StringVar(&unusedSince, "unused-since", "", "Only remove versions not activated or run for this long (e.g. 90d)")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Also remove the active version")
+	cmd.Flags().BoolVar(&opts.IncludePinned, "include-pinned", false, "Also remove pinned versions")
+
+	return cmd
+}
@@ -0,0 +1,54 @@
+package tfs
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewResolveCommand returns a new cobra.Command for the "resolve" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewResolveCommand(cache *tfs.LocalCache) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:     "resolve [version-or-constraint]",
+		Aliases: []string{"why"},
+		Short:   "Explain which Terraform version would be selected, and why",
+		Example: "resolve --json",
+		Args:    cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			var arg string
+			if len(args) != 0 {
+				arg = args[0]
+			}
+
+			explanation, err := cache.Explain(arg)
+			if explanation == nil {
+				return err
+			}
+
+			format := viper.GetString("output")
+			if jsonOutput {
+				format = tfs.OutputJSON
+			}
+			if err := tfs.WriteOutput(os.Stdout, format, explanation); err != nil {
+				return err
+			}
+
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the explanation as a JSON document (same as --output json)")
+
+	return cmd
+}
@@ -0,0 +1,22 @@
+package tfs
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewRestoreOriginalCommand returns a new cobra.Command for the "restore-original" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewRestoreOriginalCommand(cache *tfs.LocalCache) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore-original",
+		Short: "Put back the terraform binary that was backed up by 'tfs --force'",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cache.RestoreOriginal()
+		},
+	}
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package tfs
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"log/slog"
@@ -15,7 +16,16 @@ import (
 )
 
 var (
-	quiet bool
+	quiet   bool
+	offline bool
+	force   bool
+	adopt   bool
+	strict  bool
+	dryRun  bool
+	yes     bool
+	output  string
+
+	logLevel = new(slog.LevelVar)
 
 	rootCmd = &cobra.Command{
 		Use:           "tfs",
@@ -23,6 +33,13 @@ var (
 		Example:       "cd <path> && tfs",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := tfs.CheckOutputFormat(viper.GetString("output")); err != nil {
+				slog.Error("Invalid command line flag", "error", err)
+				return err
+			}
+			return nil
+		},
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return nil
@@ -44,6 +61,20 @@ var (
 func Execute() {
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", true, "Reduce logging verbosity")
 	viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Install Terraform from the offline source directory only")
+	viper.BindPFlag("offline", rootCmd.PersistentFlags().Lookup("offline"))
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Fail when a Terraform binary does not fit in the cache quota on its own")
+	viper.BindPFlag("strict", rootCmd.PersistentFlags().Lookup("strict"))
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Report the Terraform binaries that would be removed from the cache, without removing them")
+	viper.BindPFlag("dry_run", rootCmd.PersistentFlags().Lookup("dry-run"))
+	rootCmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation before removing Terraform binaries")
+	viper.BindPFlag("yes", rootCmd.PersistentFlags().Lookup("yes"))
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", tfs.OutputText, "Output format: "+strings.Join(tfs.OutputFormats, ", "))
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	rootCmd.Flags().BoolVar(&force, "force", false, "Back up an existing terraform binary that was not installed by tfs")
+	viper.BindPFlag("force", rootCmd.Flags().Lookup("force"))
+	rootCmd.Flags().BoolVar(&adopt, "adopt", false, "Back up an existing terraform binary and add it to the cache")
+	viper.BindPFlag("adopt", rootCmd.Flags().Lookup("adopt"))
 
 	// Make sure configuration is initialized.
 	tfs.InitConfig()
@@ -55,49 +86,65 @@ func Execute() {
 	cache := tfs.NewLocalCache(cacheDir)
 
 	// Add subcommands, injecting the cache instance when required.
+	rootCmd.AddCommand(NewDoctorCommand(cache))
+	rootCmd.AddCommand(NewEnvCommand(cache))
+	rootCmd.AddCommand(NewExecCommand(cache))
+	rootCmd.AddCommand(NewHookCommand())
 	rootCmd.AddCommand(NewListCommand(cache))
+	rootCmd.AddCommand(NewListRemoteCommand(cache))
+	rootCmd.AddCommand(NewLockCommand(cache))
+	rootCmd.AddCommand(NewPinCommand(cache))
 	rootCmd.AddCommand(NewPruneCommand(cache))
 	rootCmd.AddCommand(NewPruneUntilCommand(cache))
+	rootCmd.AddCommand(NewRemoveCommand(cache))
+	rootCmd.AddCommand(NewResolveCommand(cache))
+	rootCmd.AddCommand(NewRestoreOriginalCommand(cache))
+	rootCmd.AddCommand(NewUnpinCommand(cache))
+	rootCmd.AddCommand(NewVerifyCommand(cache))
 	rootCmd.AddCommand(NewVersionCommand())
 
 	// Set the root command’s RunE function to use the cache.
 	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
-		var v *version.Version
-
 		// Load the cache contents.
 		if err := cache.Load(); err != nil {
 			return err
 		}
 
-		// Determine the target Terraform version.
+		// Determine the target Terraform version. We already validated
+		// that the argument, if any, is a valid semantic version.
+		var arg string
 		if len(args) != 0 {
-			// We already validated that the argument is a valid semantic version.
-			v, _ = version.NewVersion(args[0])
-		} else {
-			// If no argument is provided, try to get the version from configuration.
-			constraintStr, err := tfs.GetTfVersionConstraint()
-			if err != nil {
-				return err
-			}
-			if v, err = tfs.ResolveVersion(constraintStr, cache.CachedVersions()); err != nil {
-				return err
-			}
+			arg = args[0]
+		}
+		sel, err := cache.SelectVersion(arg)
+		if err != nil {
+			return err
 		}
 
-		if v != nil {
-			// Create a new release in the cache.
-			release := cache.NewRelease(v)
+		// Hold the cache lock until we are done, so that another
+		// tfs process cannot remove the release we are activating.
+		unlock, err := cache.Lock()
+		if err != nil {
+			slog.Error("Failed to lock cache directory", "error", err)
+			return err
+		}
+		defer unlock()
 
-			if err := release.Install(); err != nil {
+		if sel != nil {
+			// Create a new release in the cache.
+			release, err := cache.InstallSelection(sel)
+			if err != nil {
 				return err
 			}
 			if err := release.Activate(); err != nil {
 				return err
 			}
 			// Clean up extra releases.
-			cache.AutoClean()
+			if _, err := cache.AutoClean(); err != nil {
+				slog.Warn("Cache cleanup failed", "error", err)
+			}
 		} else {
-			slog.Info("Did not find any version constraint in Terraform configuration")
+			slog.Info("Did not find any Terraform version requirement")
 			if !cache.IsEmpty() {
 				// Use the most recent version of Terraform.
 				if err := cache.LastRelease.Activate(); err != nil {
@@ -117,24 +164,28 @@ func Execute() {
 	}
 }
 
+// writeOutput prints the command report to stdout, in the selected output format.
+func writeOutput(report tfs.Report) error {
+	return tfs.WriteOutput(os.Stdout, viper.GetString("output"), report)
+}
+
 func init() {
 	cobra.OnInitialize(tfs.InitConfig)
 
 	// Logger initialization.
 	var handler slog.Handler
 
-	LogLevel := new(slog.LevelVar)
 	w := os.Stderr
 
 	if isatty.IsTerminal(w.Fd()) {
 		handler = tint.NewHandler(w, &tint.Options{
-			Level:      LogLevel,
+			Level:      logLevel,
 			NoColor:    false,
 			TimeFormat: time.Kitchen,
 		})
 	} else {
 		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{
-			Level:     LogLevel,
+			Level:     logLevel,
 			AddSource: false,
 		})
 	}
@@ -0,0 +1,25 @@
+package tfs
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/spf13/viper"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// ExecuteShim runs tfs as the terraform shim: the Terraform binary
+// required by the current directory is run with the given arguments.
+func ExecuteShim() {
+	// Stay out of the way of Terraform output.
+	logLevel.Set(slog.LevelWarn)
+
+	tfs.InitConfig()
+
+	cache := tfs.NewLocalCache(viper.GetString("cache_directory"))
+
+	if err := cache.RunShim(os.Args[1:]); err != nil {
+		slog.Error("Failed to run Terraform", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+package tfs
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yannlambret/tfs/pkg/tfs"
+)
+
+// NewVerifyCommand returns a new cobra.Command for the "verify" subcommand.
+// It receives the cache instance that will be used by the command.
+func NewVerifyCommand(cache *tfs.LocalCache) *cobra.Command {
+	var opts tfs.VerifyOptions
+
+	cmd := &cobra.Command{
+		Use:     "verify",
+		Short:   "Check the integrity of the Terraform binaries in the local cache",
+		Example: "verify --exec --quarantine",
+		Args:    cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load local cache.
+			if err := cache.Load(); err != nil {
+				return err
+			}
+
+			report, err := cache.Verify(opts)
+			if report == nil {
+				return err
+			}
+			if err := writeOutput(report); err != nil {
+				return err
+			}
+
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.ChecksumsDirectory, "checksums-dir", "", "Read the official SHA256SUMS files from this directory instead of the release source")
+	cmd.Flags().BoolVar(&opts.Exec, "exec", false, "Run each binary to make sure it reports the expected version")
+	cmd.Flags().BoolVar(&opts.Quarantine, "quarantine", false, "Move corrupted binaries to the quarantine directory")
+
+	return cmd
+}
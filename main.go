@@ -1,10 +1,19 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+
 	cmd "github.com/yannlambret/tfs/cmd/tfs"
 )
 
 func main() {
+	// Run as the terraform shim when invoked through the shim symlink.
+	if filepath.Base(os.Args[0]) == "terraform" {
+		cmd.ExecuteShim()
+		return
+	}
+
 	// Execute the CLI.
 	cmd.Execute()
 }
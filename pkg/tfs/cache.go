@@ -1,27 +1,47 @@
 package tfs
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/hashicorp/go-version"
-	"github.com/mattn/go-isatty"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
 
+// Temporary files older than this are considered
+// to be left behind by an interrupted install.
+const staleTempFileAge = 10 * time.Minute
+
+// Cache retention policies, used by the automatic cleanup.
+const (
+	// Keep the highest versions.
+	CachePolicyVersion = "version"
+
+	// Keep the most recently activated or run versions.
+	CachePolicyLRU = "lru"
+)
+
 // LocalCache holds information about downloaded Terraform releases.
 type LocalCache struct {
 	directory      string
 	releases       map[string]*release
 	activeRelease  *release
 	currentRelease *release
+	source         Source
+	lockFile       *os.File
+	lockDepth      int
+	metadata       *cacheMetadata
 	LastRelease    *release // public
 }
 
@@ -33,6 +53,33 @@ func NewLocalCache(directory string) *LocalCache {
 	}
 }
 
+// SetSource overrides the release source used to download Terraform.
+func (c *LocalCache) SetSource(s Source) {
+	c.source = s
+}
+
+// Source returns the release source used to download Terraform,
+// creating the one selected in configuration on first use.
+func (c *LocalCache) Source() (Source, error) {
+	if c.source == nil {
+		s, err := NewSource()
+		if err != nil {
+			slog.Error("Failed to initialize release source", "error", err)
+			return nil, err
+		}
+		// Keep a copy of the published versions list for remote sources.
+		if _, remote := s.(*releasesSource); remote && viper.GetDuration("remote_index_ttl") > 0 {
+			s = &indexCachedSource{
+				Source: s,
+				path:   filepath.Join(c.directory, remoteIndexFileName),
+				ttl:    viper.GetDuration("remote_index_ttl"),
+			}
+		}
+		c.source = s
+	}
+	return c.source, nil
+}
+
 // NewRelease creates a new cached release.
 func (c *LocalCache) NewRelease(v *version.Version) *release {
 	r := &release{
@@ -60,6 +107,9 @@ func (c *LocalCache) Load() error {
 	c.releases = make(map[string]*release)
 	c.LastRelease = nil
 
+	// Clean up files left behind by interrupted installs.
+	c.removeStaleTempFiles()
+
 	// Cache state.
 	files, err := afero.Glob(AppFs, filepath.Join(c.directory, viper.GetString("terraform_file_name_prefix")+"*"))
 	if err != nil {
@@ -68,6 +118,10 @@ func (c *LocalCache) Load() error {
 	}
 
 	for _, fileName := range files {
+		// Skip checksum files.
+		if strings.HasSuffix(fileName, checksumFileSuffix) {
+			continue
+		}
 		v, err := versionFromFileName(filepath.Base(fileName))
 		if err != nil {
 			fileLogger := logger.With("fileName", filepath.Base(fileName))
@@ -84,9 +138,31 @@ func (c *LocalCache) Load() error {
 		c.LastRelease = r
 	}
 
+	versions := make([]string, 0, len(c.releases))
+	for v := range c.releases {
+		versions = append(versions, v)
+	}
+	c.metadata = c.readMetadata(versions)
+
 	return nil
 }
 
+// removeStaleTempFiles removes the temporary files that have not been
+// written to for a while. Recent ones may belong to an install in progress.
+func (c *LocalCache) removeStaleTempFiles() {
+	files, _ := afero.Glob(AppFs, filepath.Join(c.directory, ".*"+tempFileSuffix))
+
+	for _, fileName := range files {
+		fi, err := AppFs.Stat(fileName)
+		if err != nil || time.Since(fi.ModTime()) < staleTempFileAge {
+			continue
+		}
+		if err := AppFs.Remove(fileName); err == nil {
+			slog.Info("Removed leftover temporary file", "cacheDirectory", c.directory, "fileName", filepath.Base(fileName))
+		}
+	}
+}
+
 // IsEmpty allows to check if the cache is empty.
 func (c *LocalCache) IsEmpty() bool {
 	return len(c.releases) == 0
@@ -101,31 +177,162 @@ func (c *LocalCache) CachedVersions() []*version.Version {
 	return versions
 }
 
-// List command displays the contents of the local cache.
-func (c *LocalCache) List() error {
-	versions := make([]*version.Version, 0, len(c.releases))
+// ReleaseInfo describes a cached release.
+type ReleaseInfo struct {
+	Version     string     `json:"version"`
+	Size        uint64     `json:"size"`
+	InstalledAt time.Time  `json:"installedAt"`
+	LastUsed    *time.Time `json:"lastUsed,omitempty"`
+	Active      bool       `json:"active"`
+	Pinned      bool       `json:"pinned"`
+	Source      string     `json:"source,omitempty"`
+	Projects    []string   `json:"projects,omitempty"`
+}
+
+// ReleaseList is the report of the "list" command.
+type ReleaseList []ReleaseInfo
+
+// WriteText writes one version per line, highlighting the active one.
+func (l ReleaseList) WriteText(w io.Writer) error {
+	for _, info := range l {
+		line := info.Version
+		if info.Pinned {
+			line += " (pinned)"
+		}
+
+		var err error
+		if info.Active {
+			_, err = color.New(color.FgHiCyan, color.Bold).Fprintln(w, line+" (active)")
+		} else {
+			_, err = fmt.Fprintln(w, line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l ReleaseList) tableRows() [][]string {
+	rows := [][]string{{"VERSION", "SIZE", "INSTALLED", "LAST USED", "ACTIVE", "PINNED", "SOURCE"}}
+	for _, info := range l {
+		var lastUsed time.Time
+		if info.LastUsed != nil {
+			lastUsed = *info.LastUsed
+		}
+		rows = append(rows, []string{info.Version, formatSize(info.Size), formatTime(info.InstalledAt), formatTime(lastUsed), formatBool(info.Active), formatBool(info.Pinned), formatString(info.Source)})
+	}
+	return rows
+}
+
+// List returns the contents of the local cache, sorted by version.
+func (c *LocalCache) List() (ReleaseList, error) {
+	list := make(ReleaseList, 0, len(c.releases))
+
+	for _, r := range c.sortedReleases() {
+		info, err := r.info()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+
+	return list, nil
+}
+
+// sortedReleases returns the cached releases, sorted by version.
+func (c *LocalCache) sortedReleases() []*release {
+	releases := make([]*release, 0, len(c.releases))
 	for _, r := range c.releases {
-		versions = append(versions, r.Version)
+		releases = append(releases, r)
+	}
+	return sortByVersion(releases)
+}
+
+// RemoteReleaseInfo describes a published release.
+type RemoteReleaseInfo struct {
+	Version string `json:"version"`
+	Cached  bool   `json:"cached"`
+	Active  bool   `json:"active"`
+}
+
+// RemoteReleaseList is the report of the "list-remote" command.
+type RemoteReleaseList []RemoteReleaseInfo
+
+// WriteText writes one version per line, highlighting the cached ones.
+func (l RemoteReleaseList) WriteText(w io.Writer) error {
+	for _, info := range l {
+		var err error
+		switch {
+		case info.Active:
+			_, err = color.New(color.FgHiCyan, color.Bold).Fprintln(w, info.Version+" (active)")
+		case info.Cached:
+			_, err = color.New(color.FgCyan).Fprintln(w, info.Version+" (cached)")
+		default:
+			_, err = fmt.Fprintln(w, info.Version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l RemoteReleaseList) tableRows() [][]string {
+	rows := [][]string{{"VERSION", "CACHED", "ACTIVE"}}
+	for _, info := range l {
+		rows = append(rows, []string{info.Version, formatBool(info.Cached), formatBool(info.Active)})
+	}
+	return rows
+}
+
+// ListRemote returns the Terraform versions published by the release source.
+// Only the most recent versions are returned when limit is positive.
+func (c *LocalCache) ListRemote(constraintStr string, includePrereleases bool, limit int) (RemoteReleaseList, error) {
+	var constraint version.Constraints
+
+	if constraintStr != "" {
+		var err error
+		if constraint, err = version.NewConstraint(constraintStr); err != nil {
+			slog.Error("Failed to parse Terraform version constraint", "error", err, "constraint", constraintStr)
+			return nil, err
+		}
+	}
+
+	remote, err := c.RemoteVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*version.Version, 0, len(remote))
+	for _, v := range remote {
+		if v.Prerelease() != "" && !includePrereleases {
+			continue
+		}
+		// Pre-releases never satisfy a constraint that does not mention
+		// one, so we check their core version when they are requested.
+		if constraint != nil && !constraint.Check(v) && !(includePrereleases && constraint.Check(v.Core())) {
+			continue
+		}
+		versions = append(versions, v)
 	}
 	sort.Sort(version.Collection(versions))
 
+	if limit > 0 && len(versions) > limit {
+		versions = versions[len(versions)-limit:]
+	}
+
+	list := make(RemoteReleaseList, 0, len(versions))
 	for _, v := range versions {
-		r := c.releases[v.String()]
-		if isatty.IsTerminal(os.Stderr.Fd()) {
-			if r.SameAs(c.activeRelease) {
-				color.New(color.FgHiCyan, color.Bold).Println(v.String() + " (active)")
-			} else {
-				fmt.Println(v.String())
-			}
-		} else {
-			slog.Info("release",
-				slog.String("version", v.String()),
-				slog.Bool("isActive", r.SameAs(c.activeRelease)),
-			)
-		}
+		r, cached := c.releases[v.String()]
+		list = append(list, RemoteReleaseInfo{
+			Version: v.String(),
+			Cached:  cached,
+			Active:  cached && r.SameAs(c.activeRelease),
+		})
 	}
 
-	return nil
+	return list, nil
 }
 
 // Size returns the cache total size.
@@ -146,85 +353,214 @@ func (c *LocalCache) Size() (uint64, error) {
 	return size, nil
 }
 
-// Prune command can be used to wipe the whole cache.
-func (c *LocalCache) Prune() error {
-	var (
-		removed   int
-		reclaimed uint64
-	)
+// PruneReport is the report of the commands removing releases.
+type PruneReport struct {
+	Removed        []ReleaseInfo `json:"removed"`
+	ReclaimedBytes uint64        `json:"reclaimedBytes"`
+	CacheSize      uint64        `json:"cacheSize"`
 
-	for _, release := range c.releases {
-		releaseSize, err := release.Size()
-		if err != nil {
-			return err
-		}
-		if err := release.Remove(); err != nil {
-			return err
-		}
-		removed++
-		reclaimed += releaseSize
+	// Nothing was removed: the report tells what would be.
+	DryRun bool `json:"dryRun"`
+
+	// Releases that could not be removed.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// WriteText writes one removed version per line, and the reclaimed space.
+func (p *PruneReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	verb, summary := "Removed", "Reclaimed"
+	if p.DryRun {
+		verb, summary = "Would remove", "Would reclaim"
+	}
+	for _, info := range p.Removed {
+		fmt.Fprintf(&b, "%s %s (%s)\n", verb, info.Version, formatSize(info.Size))
+	}
+	for _, e := range p.Errors {
+		fmt.Fprintf(&b, "Failed to remove %s\n", e)
+	}
+	if len(p.Removed) > 0 {
+		fmt.Fprintf(&b, "%s %s, %s left in cache\n", summary, formatSize(p.ReclaimedBytes), formatSize(p.CacheSize))
 	}
 
-	cacheSize, err := c.Size()
-	if err != nil {
-		return err
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *PruneReport) tableRows() [][]string {
+	rows := [][]string{{"VERSION", "SIZE"}}
+	for _, info := range p.Removed {
+		rows = append(rows, []string{info.Version, formatSize(info.Size)})
+	}
+	return rows
+}
+
+// log writes a summary of the report.
+func (p *PruneReport) log(directory string) {
+	msg := "Removed %d file(s)"
+	if p.DryRun {
+		msg = "Dry run: would remove %d file(s)"
 	}
 
 	slog.Info(
-		"Removed "+fmt.Sprintf("%d", removed)+" file(s)",
-		"cacheDirectory", c.directory,
-		"cacheSize", formatSize(cacheSize),
-		"reclaimedSpace", formatSize(reclaimed),
-		"removed", removed,
+		fmt.Sprintf(msg, len(p.Removed)),
+		"cacheDirectory", directory,
+		"cacheSize", formatSize(p.CacheSize),
+		"reclaimedSpace", formatSize(p.ReclaimedBytes),
+		"removed", len(p.Removed),
 	)
+}
 
-	return nil
+// Prune command can be used to wipe the whole cache.
+// Pinned releases are kept, unless includePinned is set.
+func (c *LocalCache) Prune(includePinned bool) (*PruneReport, error) {
+	return c.removeReleases(includePinned, func(r *release) bool {
+		return true
+	})
 }
 
 // PruneUntil command removes all Terraform binary versions prior to the one specified.
-func (c *LocalCache) PruneUntil(v *version.Version) error {
+// Pinned releases are kept, unless includePinned is set.
+func (c *LocalCache) PruneUntil(v *version.Version, includePinned bool) (*PruneReport, error) {
+	return c.removeReleases(includePinned, func(r *release) bool {
+		return r.Version.LessThan(v)
+	})
+}
+
+// removeReleases removes the releases matching the filter, in version order,
+// once the user has confirmed it.
+func (c *LocalCache) removeReleases(includePinned bool, filter func(r *release) bool) (*PruneReport, error) {
 	var (
-		removed   int
-		reclaimed uint64
+		releases []*release
+		size     uint64
 	)
 
-	for _, release := range c.releases {
-		if release.Version.LessThan(v) {
-			releaseSize, err := release.Size()
-			if err != nil {
-				return err
-			}
-			if err := release.Remove(); err != nil {
-				return err
-			}
-			removed++
-			reclaimed += releaseSize
+	for _, release := range c.sortedReleases() {
+		if !filter(release) {
+			continue
+		}
+		if release.isPinned() && !includePinned {
+			slog.Info("Keeping pinned release", "version", release.Version.String())
+			continue
+		}
+		// Failures are reported when removing the release.
+		releaseSize, _ := release.Size()
+		releases = append(releases, release)
+		size += releaseSize
+	}
+
+	if len(releases) > 0 && !viper.GetBool("dry_run") {
+		prompt := fmt.Sprintf("Remove %d Terraform release(s) (%s), reclaiming %s?", len(releases), joinVersions(releases), formatSize(size))
+		if err := confirm(prompt); err != nil {
+			return nil, err
 		}
 	}
 
-	cacheSize, err := c.Size()
+	unlock, err := c.Lock()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer unlock()
 
-	slog.Info(
-		"Removed "+fmt.Sprintf("%d", removed)+" file(s)",
-		"cacheDirectory", c.directory,
-		"cacheSize", formatSize(cacheSize),
-		"reclaimedSpace", formatSize(reclaimed),
-		"removed", removed,
-	)
+	report, err := c.removeAll(releases)
+	report.log(c.directory)
 
-	return nil
+	return report, err
+}
+
+// removeAll removes the given releases, or only reports what would be
+// removed when the "dry_run" setting is set. A failure does not prevent
+// the other releases from being removed: all errors are returned.
+func (c *LocalCache) removeAll(releases []*release) (*PruneReport, error) {
+	report := &PruneReport{
+		Removed: make([]ReleaseInfo, 0),
+		DryRun:  viper.GetBool("dry_run"),
+	}
+
+	var errs []error
+
+	for _, r := range releases {
+		info, err := r.info()
+		if err == nil && !report.DryRun {
+			err = r.Remove()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Terraform %s: %w", r.Version, err))
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", r.Version, err))
+			continue
+		}
+		if report.DryRun {
+			slog.Info("Would remove Terraform binary", "version", info.Version, "size", formatSize(info.Size))
+		}
+		report.Removed = append(report.Removed, info)
+		report.ReclaimedBytes += info.Size
+	}
+
+	// Unknown when a release could not be removed.
+	if size, err := c.Size(); err == nil {
+		report.CacheSize = size
+		if report.DryRun {
+			report.CacheSize -= report.ReclaimedBytes
+		}
+	}
+
+	return report, errors.Join(errs...)
 }
 
-func (c *LocalCache) AutoClean() {
+// AutoClean removes the releases the retention policy does not keep,
+// and makes sure the cache fits in its quota. The active release and
+// the one just installed are never removed, and neither are pinned ones.
+func (c *LocalCache) AutoClean() (*PruneReport, error) {
+	unlock, err := c.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	// Reload cache contents.
-	c.Load()
+	if err := c.Load(); err != nil {
+		return nil, err
+	}
+
+	var releases []*release
+	if viper.GetBool("cache_auto_clean") {
+		releases = c.retentionEvictions()
+	}
+
+	// The quota is honoured even when the cleanup is disabled.
+	quotaReleases, err := c.quotaEvictions(nil, 0, releases)
+	if err != nil {
+		slog.Warn("Failed to enforce cache quota", "error", err)
+	}
+	releases = append(releases, quotaReleases...)
+
+	report, err := c.removeAll(releases)
+	if len(releases) > 0 {
+		report.log(c.directory)
+	}
+
+	return report, err
+}
+
+// retentionEvictions returns the releases the retention policy does not keep.
+func (c *LocalCache) retentionEvictions() []*release {
+	var evictions []*release
+
+	evict := func(r *release) {
+		if r.SameAs(c.currentRelease) || r.SameAs(c.activeRelease) || r.isPinned() || slices.Contains(evictions, r) {
+			return
+		}
+		evictions = append(evictions, r)
+	}
 
-	if !viper.GetBool("cache_auto_clean") {
-		// Feature disabled.
-		return
+	switch policy := viper.GetString("cache_policy"); policy {
+	case "", CachePolicyVersion:
+	case CachePolicyLRU:
+		return c.leastRecentlyUsed(viper.GetInt("cache_history"))
+	default:
+		slog.Warn("Skipping cache cleanup", "error", fmt.Errorf("unknown cache policy %q", policy))
+		return nil
 	}
 
 	minorLimit := viper.GetInt("cache_minor_version_nb")
@@ -259,13 +595,12 @@ func (c *LocalCache) AutoClean() {
 		// Drop the oldest minor releases if needed.
 		if n := len(minorKeys) - viper.GetInt("cache_minor_version_nb"); n > 0 {
 			for _, v := range minorKeys[:n] {
-				constraint, _ := version.NewConstraint(fmt.Sprintf("~> %s", v.String()))
-				for _, release := range c.releases {
-					if constraint.Check(release.Version) && !release.SameAs(c.currentRelease) {
-						release.Remove()
-					}
+				segments := v.Segments()
+				minorKey := fmt.Sprintf("%d.%d", segments[0], segments[1])
+				for _, v := range minorReleases[minorKey] {
+					evict(c.releases[v.String()])
 				}
-				delete(minorReleases, v.String())
+				delete(minorReleases, minorKey)
 			}
 		}
 
@@ -273,31 +608,68 @@ func (c *LocalCache) AutoClean() {
 		for _, versions := range minorReleases {
 			if n := len(versions) - viper.GetInt("cache_patch_version_nb"); n > 0 {
 				for _, v := range versions[:n] {
-					if r, ok := c.releases[v.String()]; ok && !r.SameAs(c.currentRelease) {
-						r.Remove()
-					}
+					evict(c.releases[v.String()])
 				}
 			}
 		}
 
-		return
+		return sortByVersion(evictions)
 	}
 
 	// Default caching mode.
 	cacheHistory := viper.GetInt("cache_history")
 
 	if n := len(c.releases) - cacheHistory; n > 0 {
-		versions := make([]*version.Version, 0, len(c.releases))
-		for _, r := range c.releases {
-			versions = append(versions, r.Version)
+		for _, r := range c.sortedReleases()[:n] {
+			evict(r)
 		}
-		sort.Sort(version.Collection(versions))
-		for _, v := range versions[:n] {
-			if r, ok := c.releases[v.String()]; ok && !r.SameAs(c.currentRelease) {
-				r.Remove()
-			}
+	}
+
+	return evictions
+}
+
+// leastRecentlyUsed returns the releases to remove so that the given number
+// of releases is kept, the ones activated or run the longest time ago first.
+// The active release and the one just installed are never returned, and
+// neither are pinned ones.
+func (c *LocalCache) leastRecentlyUsed(keep int) []*release {
+	var evictions []*release
+
+	releases := c.sortedReleases()
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].lastUse().Before(releases[j].lastUse())
+	})
+
+	n := len(releases) - keep
+	for _, r := range releases {
+		if n <= 0 {
+			break
 		}
+		if r.SameAs(c.currentRelease) || r.SameAs(c.activeRelease) || r.isPinned() {
+			continue
+		}
+		evictions = append(evictions, r)
+		n--
 	}
+
+	return evictions
+}
+
+// sortByVersion sorts releases by version.
+func sortByVersion(releases []*release) []*release {
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Version.LessThan(releases[j].Version)
+	})
+	return releases
+}
+
+// joinVersions returns the versions of the given releases, separated by commas.
+func joinVersions(releases []*release) string {
+	versions := make([]string, len(releases))
+	for i, r := range releases {
+		versions[i] = r.Version.String()
+	}
+	return strings.Join(versions, ", ")
 }
 
 // versionFromFileName extracts semantic version from Terraform binary name.
@@ -309,3 +681,27 @@ func versionFromFileName(fileName string) (*version.Version, error) {
 func formatSize(size uint64) string {
 	return humanize.Bytes(size)
 }
+
+// formatTime returns a timestamp in a compact form, for tables.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// formatBool returns a flag value for tables.
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// formatString returns a possibly empty value for tables.
+func formatString(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -3,6 +3,7 @@ package tfs
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/spf13/afero"
@@ -82,10 +83,18 @@ func TestCachePrune(t *testing.T) {
 		t.Fatalf("Cache.Load() failed: %v", err)
 	}
 
-	if err := cache.Prune(); err != nil {
+	report, err := cache.Prune(false)
+	if err != nil {
 		t.Fatalf("Cache.Prune() failed: %v", err)
 	}
 
+	if len(report.Removed) != 2 || report.Removed[0].Version != "1.9.0" || report.Removed[1].Version != "1.10.0" {
+		t.Errorf("Expected 1.9.0 and 1.10.0 to be reported as removed, got %+v", report.Removed)
+	}
+	if report.ReclaimedBytes != uint64(2*len("dummy content")) || report.CacheSize != 0 {
+		t.Errorf("Unexpected reclaimed space %d and cache size %d", report.ReclaimedBytes, report.CacheSize)
+	}
+
 	for _, v := range versions {
 		filePath := filepath.Join(cacheDir, testFilePrefix+v)
 		if exists, _ := afero.Exists(AppFs, filePath); exists {
@@ -113,10 +122,15 @@ func TestCachePruneUntil(t *testing.T) {
 	// Prune until version 1.10.0.
 	v110, _ := version.NewVersion("1.10.0")
 
-	if err := cache.PruneUntil(v110); err != nil {
+	report, err := cache.PruneUntil(v110, false)
+	if err != nil {
 		t.Fatalf("Cache.PruneUntil() failed: %v", err)
 	}
 
+	if len(report.Removed) != 1 || report.Removed[0].Version != "1.9.0" {
+		t.Errorf("Expected only 1.9.0 to be reported as removed, got %+v", report.Removed)
+	}
+
 	if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.9.0")); exists {
 		t.Errorf("Expected 1.9.0 to be pruned")
 	}
@@ -237,3 +251,107 @@ func TestCacheAutoClean_PatchVersionLimit(t *testing.T) {
 		}
 	})
 }
+
+func TestCacheAutoClean_KeepsActiveRelease(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_auto_clean", true)
+	viper.Set("cache_minor_version_nb", 1)
+	viper.Set("cache_patch_version_nb", 99)
+
+	for _, v := range []string{"1.9.0", "1.10.0"} {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte("dummy content"))
+	}
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+	if err := cache.releases["1.9.0"].Activate(); err != nil {
+		t.Fatalf("Release.Activate() failed: %v", err)
+	}
+
+	cache.AutoClean()
+
+	if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.9.0")); !exists {
+		t.Errorf("Expected active version 1.9.0 to remain")
+	}
+}
+
+func TestCacheAutoClean_LRUPolicy(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_auto_clean", true)
+	viper.Set("cache_policy", CachePolicyLRU)
+	viper.Set("cache_history", 2)
+
+	for _, v := range []string{"0.13.7", "1.5.0", "1.9.0-beta1", "1.10.0"} {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte("dummy content"))
+	}
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+	if err := cache.releases["1.10.0"].Activate(); err != nil {
+		t.Fatalf("Release.Activate() failed: %v", err)
+	}
+
+	// 1.10.0 is the least recently used release, but it is active.
+	writeTestFile(t, filepath.Join(cacheDir, metadataFileName), []byte(`{
+  "formatVersion": 1,
+  "releases": {
+    "0.13.7": {"installedAt": "2020-01-01T00:00:00Z", "lastUsed": "2026-10-01T00:00:00Z"},
+    "1.5.0": {"installedAt": "2023-01-01T00:00:00Z", "lastUsed": "2024-01-01T00:00:00Z"},
+    "1.9.0-beta1": {"installedAt": "2025-01-01T00:00:00Z"},
+    "1.10.0": {"installedAt": "2020-01-01T00:00:00Z", "lastUsed": "2020-01-01T00:00:00Z"}
+  }
+}`))
+
+	cache.AutoClean()
+
+	for _, v := range []string{"0.13.7", "1.10.0"} {
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v)); !exists {
+			t.Errorf("Expected version %s to remain", v)
+		}
+	}
+	for _, v := range []string{"1.5.0", "1.9.0-beta1"} {
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v)); exists {
+			t.Errorf("Expected version %s to be removed", v)
+		}
+	}
+}
+
+func TestCacheLoadRemovesStaleTempFiles(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	stale := filepath.Join(cacheDir, "."+testFilePrefix+"1.9.0.123"+tempFileSuffix)
+	recent := filepath.Join(cacheDir, "."+testFilePrefix+"1.10.0.456"+tempFileSuffix)
+
+	writeTestFile(t, stale, []byte("partial"))
+	writeTestFile(t, recent, []byte("partial"))
+
+	old := time.Now().Add(-2 * staleTempFileAge)
+	if err := AppFs.Chtimes(stale, old, old); err != nil {
+		t.Fatalf("Failed to change file times: %v", err)
+	}
+
+	cache := NewLocalCache(cacheDir)
+
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	if !cache.IsEmpty() {
+		t.Errorf("Expected temporary files not to be loaded as releases")
+	}
+	if exists, _ := afero.Exists(AppFs, stale); exists {
+		t.Errorf("Expected stale temporary file to be removed")
+	}
+	if exists, _ := afero.Exists(AppFs, recent); !exists {
+		t.Errorf("Expected recent temporary file to remain")
+	}
+}
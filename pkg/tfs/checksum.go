@@ -0,0 +1,154 @@
+package tfs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// HashiCorp public key used to sign release checksums.
+// See https://www.hashicorp.com/security
+//
+//go:embed hashicorp.asc
+var hashicorpPublicKey string
+
+// Checksum files are stored next to the cached binaries.
+const checksumFileSuffix = ".sha256"
+
+// ErrChecksumMismatch is returned when a file does not match its recorded checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Checksums maps file names to their hex-encoded SHA256 sums,
+// as listed in a SHA256SUMS file.
+type Checksums map[string]string
+
+// parseChecksums reads the contents of a SHA256SUMS file.
+func parseChecksums(r io.Reader) (Checksums, error) {
+	sums := make(Checksums)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected checksum line format: %q", line)
+		}
+		if b, err := hex.DecodeString(fields[0]); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid SHA256 sum: %q", fields[0])
+		}
+		sums[fields[1]] = fields[0]
+	}
+
+	return sums, scanner.Err()
+}
+
+// String formats checksums the same way as the sha256sum tool.
+func (c Checksums) String() string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	// Keep a stable output.
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", c[name], name)
+	}
+	return b.String()
+}
+
+// trustedKeyRing returns the keys allowed to sign release checksums:
+// the HashiCorp key, plus the ones listed in the "trusted_pgp_keys" setting.
+func trustedKeyRing() (openpgp.EntityList, error) {
+	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(hashicorpPublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range viper.GetStringSlice("trusted_pgp_keys") {
+		b, err := afero.ReadFile(AppFs, path)
+		if err != nil {
+			slog.Error("Failed to read trusted PGP key", "error", err, "fileName", path)
+			return nil, err
+		}
+		keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+		if err != nil {
+			slog.Error("Failed to parse trusted PGP key", "error", err, "fileName", path)
+			return nil, err
+		}
+		keyRing = append(keyRing, keys...)
+	}
+
+	return keyRing, nil
+}
+
+// verifyChecksums checks the detached signature of a SHA256SUMS
+// file against the trusted keys, and returns its contents.
+func verifyChecksums(sums, signature []byte) (Checksums, error) {
+	keyRing, err := trustedKeyRing()
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := openpgp.CheckDetachedSignature(keyRing, bytes.NewReader(sums), bytes.NewReader(signature), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify checksums signature: %w", err)
+	}
+	slog.Debug("Checksums signature is valid", "keyId", signer.PrimaryKey.KeyIdString())
+
+	return parseChecksums(bytes.NewReader(sums))
+}
+
+// sha256Sum returns the hex-encoded SHA256 sum of b.
+func sha256Sum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// sha256File returns the hex-encoded SHA256 sum of the given file.
+func sha256File(path string) (string, error) {
+	f, err := AppFs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readChecksumFile reads the checksums recorded next to a cached binary.
+// Returns nil, nil if no checksum was recorded.
+func readChecksumFile(path string) (Checksums, error) {
+	f, err := AppFs.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseChecksums(f)
+}
@@ -0,0 +1,175 @@
+package tfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/spf13/viper"
+)
+
+// newTestKey creates a PGP key and registers it as a trusted key.
+func newTestKey(t *testing.T, dir string) *openpgp.Entity {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("tfs test", "", "tfs@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create PGP key: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("Failed to armor PGP key: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("Failed to serialize PGP key: %v", err)
+	}
+	w.Close()
+
+	path := filepath.Join(dir, "trusted.asc")
+	writeTestFile(t, path, buf.Bytes())
+	viper.Set("trusted_pgp_keys", []string{path})
+
+	return entity
+}
+
+// sign returns the detached signature of b.
+func sign(t *testing.T, entity *openpgp.Entity, b []byte) []byte {
+	t.Helper()
+	var sig bytes.Buffer
+	if err := openpgp.DetachSign(&sig, entity, bytes.NewReader(b), nil); err != nil {
+		t.Fatalf("Failed to sign checksums: %v", err)
+	}
+	return sig.Bytes()
+}
+
+// newTestReleasesServer serves a single Terraform release following
+// the layout of the HashiCorp releases API.
+func newTestReleasesServer(t *testing.T, entity *openpgp.Entity, archive []byte, sumsArchive []byte) *httptest.Server {
+	t.Helper()
+
+	v := mustVersion(t, "1.10.0")
+	sums := []byte(fmt.Sprintf("%s  %s\n", sha256Sum(sumsArchive), archiveName(v)))
+	files := map[string][]byte{
+		"/terraform/1.10.0/index.json": []byte(fmt.Sprintf(
+			`{"name":"terraform","version":"1.10.0","shasums":"%s","shasums_signature":"%s.sig"}`,
+			checksumsName(v), checksumsName(v),
+		)),
+		"/terraform/1.10.0/" + checksumsName(v):          sums,
+		"/terraform/1.10.0/" + checksumsName(v) + ".sig": sign(t, entity, sums),
+		"/terraform/1.10.0/" + archiveName(v):            archive,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+}
+
+// testArchive returns a release archive holding the given binary.
+func testArchive(t *testing.T, binary string) []byte {
+	t.Helper()
+	path := filepath.Join(t.Name(), binary)
+	writeTestArchive(t, path, map[string]string{"terraform": binary})
+	b, err := readTestFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	return b
+}
+
+func TestParseChecksums(t *testing.T) {
+	sum := sha256Sum([]byte("content"))
+
+	sums, err := parseChecksums(strings.NewReader(sum + "  terraform_1.10.0_linux_amd64.zip\n\n"))
+	if err != nil {
+		t.Fatalf("parseChecksums() failed: %v", err)
+	}
+	if sums["terraform_1.10.0_linux_amd64.zip"] != sum {
+		t.Errorf("Unexpected checksum %q", sums["terraform_1.10.0_linux_amd64.zip"])
+	}
+	if sums.String() != sum+"  terraform_1.10.0_linux_amd64.zip\n" {
+		t.Errorf("Unexpected checksums format %q", sums.String())
+	}
+
+	for _, invalid := range []string{"abcd  file.zip", "no-file-name", sum + " a b"} {
+		if _, err := parseChecksums(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected error for checksum line %q", invalid)
+		}
+	}
+}
+
+func TestReleasesSourceFetchVerified(t *testing.T) {
+	tempDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	entity := newTestKey(t, tempDir)
+	archive := testArchive(t, "terraform 1.10.0")
+
+	server := newTestReleasesServer(t, entity, archive, archive)
+	defer server.Close()
+
+	s := &releasesSource{baseURL: server.URL}
+
+	var buf bytes.Buffer
+	artifact, err := s.Fetch(context.Background(), mustVersion(t, "1.10.0"), &buf)
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if !artifact.Verified || artifact.SHA256 != sha256Sum(archive) {
+		t.Errorf("Unexpected artifact %+v", artifact)
+	}
+	if buf.String() != "terraform 1.10.0" {
+		t.Errorf("Unexpected binary content %q", buf.String())
+	}
+}
+
+func TestReleasesSourceFetchTamperedArchive(t *testing.T) {
+	tempDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	entity := newTestKey(t, tempDir)
+	archive := testArchive(t, "terraform 1.10.0")
+
+	server := newTestReleasesServer(t, entity, testArchive(t, "malicious"), archive)
+	defer server.Close()
+
+	s := &releasesSource{baseURL: server.URL}
+
+	if _, err := s.Fetch(context.Background(), mustVersion(t, "1.10.0"), &bytes.Buffer{}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestReleasesSourceFetchUntrustedSignature(t *testing.T) {
+	tempDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	entity := newTestKey(t, tempDir)
+	archive := testArchive(t, "terraform 1.10.0")
+
+	server := newTestReleasesServer(t, entity, archive, archive)
+	defer server.Close()
+
+	// Only trust the HashiCorp key.
+	viper.Set("trusted_pgp_keys", []string{})
+
+	s := &releasesSource{baseURL: server.URL}
+
+	if _, err := s.Fetch(context.Background(), mustVersion(t, "1.10.0"), &bytes.Buffer{}); err == nil {
+		t.Fatalf("Expected signature verification to fail")
+	}
+}
@@ -1,9 +1,13 @@
 package tfs
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/go-version"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
@@ -15,8 +19,10 @@ func initTestFS(tb testing.TB) (string, func()) {
 	tb.Helper()
 	tempDir := tb.TempDir()
 
-	// Create a real OS-based filesystem using a temp directory.
-	AppFs = &AferoFs{Fs: afero.NewBasePathFs(afero.NewOsFs(), tempDir)}
+	// Use the real filesystem, so that links created through AppFs
+	// resolve the same way as with the os package. Every test path
+	// is rooted in the temp directory.
+	AppFs = &AferoFs{Fs: afero.NewOsFs()}
 
 	// Set required Viper config values.
 	viper.Set("terraform_file_name_prefix", testFilePrefix)
@@ -38,3 +44,63 @@ func writeTestFile(tb testing.TB, path string, content []byte) {
 		tb.Fatalf("Failed to write file: %v", err)
 	}
 }
+
+// fakeSource is an in-memory release source.
+type fakeSource struct {
+	binaries map[string][]byte
+	fetched  []string
+	listed   int
+	err      error
+}
+
+func newFakeSource(versions ...string) *fakeSource {
+	s := &fakeSource{binaries: make(map[string][]byte)}
+	for _, v := range versions {
+		s.binaries[v] = []byte("terraform " + v)
+	}
+	return s
+}
+
+func (s *fakeSource) Name() string {
+	return "fake"
+}
+
+func (s *fakeSource) Versions(ctx context.Context) ([]*version.Version, error) {
+	s.listed++
+	if s.err != nil {
+		return nil, s.err
+	}
+	versions := make([]*version.Version, 0, len(s.binaries))
+	for raw := range s.binaries {
+		versions = append(versions, version.Must(version.NewVersion(raw)))
+	}
+	return versions, nil
+}
+
+func (s *fakeSource) Fetch(ctx context.Context, v *version.Version, w io.Writer) (*Artifact, error) {
+	b, ok := s.binaries[v.String()]
+	if !ok {
+		return nil, fmt.Errorf("version %s not found", v)
+	}
+	s.fetched = append(s.fetched, v.String())
+	artifact := &Artifact{
+		URL:      "fake://" + archiveName(v),
+		FileName: archiveName(v),
+		SHA256:   sha256Sum(b),
+		Verified: true,
+	}
+	_, err := w.Write(b)
+	return artifact, err
+}
+
+func readTestFile(path string) ([]byte, error) {
+	return afero.ReadFile(AppFs, path)
+}
+
+func (s *fakeSource) Checksums(ctx context.Context, v *version.Version) (Checksums, error) {
+	b, ok := s.binaries[v.String()]
+	if !ok {
+		return nil, fmt.Errorf("version %s not found", v)
+	}
+	return Checksums{archiveName(v): sha256Sum(b)}, nil
+}
@@ -11,6 +11,9 @@ import (
 // Current software version.
 const tfsVersion = "v1.4.2"
 
+// Settings known to tfs, used to detect typos in the configuration file.
+var knownConfigKeys []string
+
 func InitConfig() {
 	userHomeDir, err := os.UserHomeDir()
 
@@ -51,8 +54,7 @@ func InitConfig() {
 	// User-specific configurations directory.
 	viper.SetDefault("user_config_directory", userConfigDir)
 
-	// User-specific executable files directory.
-	// TODO: check if the folder belongs to the PATH variable, raise a warning otherwise.
+	// User-specific executable files directory ('tfs doctor' checks that it is on PATH).
 	viper.SetDefault("user_bin_directory", filepath.Join(userHomeDir, ".local", "bin"))
 
 	// Application configuration directory.
@@ -67,14 +69,85 @@ func InitConfig() {
 	// Keep a limited number of release files in the cache.
 	viper.SetDefault("cache_auto_clean", true)
 
+	// Which releases are kept in the cache: "version" keeps the highest
+	// versions, "lru" keeps the most recently activated or run ones.
+	viper.SetDefault("cache_policy", "version")
+
 	// Number of Terraform releases to keep.
 	// Most recent releases will be kept in the cache.
 	viper.SetDefault("cache_history", 8)
 	viper.SetDefault("cache_minor_version_nb", 0)
 	viper.SetDefault("cache_patch_version_nb", 0)
 
+	// Versions never removed from the cache, in addition
+	// to the ones pinned with "tfs pin".
+	viper.SetDefault("pinned_versions", []string{})
+
+	// Maximum size of the cached binaries (e.g. "1GiB"), none when empty.
+	// Releases are removed following the retention policy to fit in it.
+	viper.SetDefault("cache_max_size", "")
+
+	// How long to wait for another tfs process to release
+	// the cache before giving up.
+	viper.SetDefault("cache_lock_timeout", "2m")
+
+	// Where Terraform binaries are downloaded from: "hashicorp" for
+	// the official releases API, or "mirror" for any server following
+	// the same layout (see "release_source_url").
+	viper.SetDefault("release_source", "hashicorp")
+	viper.SetDefault("release_source_url", "")
+
+	// Maximum time a request to the release source may take, including
+	// the download of the release archive. No limit when zero.
+	viper.SetDefault("release_source_timeout", "10m")
+
+	// Air-gapped mode: install Terraform from a directory holding
+	// the official release archives, without any network access.
+	viper.SetDefault("offline", false)
+	viper.SetDefault("offline_source_directory", "")
+
+	// Install archives from the offline source directory even when their
+	// signed checksums are missing. Provenance is not checked then.
+	viper.SetDefault("offline_allow_unverified", false)
+
+	// How version constraints are resolved: "cached-first" looks for
+	// a matching release in the cache before checking published releases,
+	// "remote-first" picks the highest published release, and "cached-only"
+	// never looks beyond the cache.
+	viper.SetDefault("version_resolution", "cached-first")
+
+	// Version requirements are looked for in the current directory and its
+	// parents, up to the repository root, the home directory (excluded) or
+	// this directory, whichever comes first.
+	viper.SetDefault("version_search_boundary", "")
+
+	// How long the list of published releases is kept in the cache
+	// directory before querying the release source again.
+	viper.SetDefault("remote_index_ttl", "1h")
+
+	// Armored PGP public keys trusted to sign release checksums,
+	// in addition to the HashiCorp key (e.g. the key of an internal mirror).
+	viper.SetDefault("trusted_pgp_keys", []string{})
+
+	// How the Terraform binary is activated: "symlink" points the terraform
+	// symlink to the selected binary, "shim" points it to tfs, which runs the
+	// binary required by the current directory each time terraform is run.
+	viper.SetDefault("activation_mode", "symlink")
+
+	// Let the shim install the required version when it is missing.
+	viper.SetDefault("shim_auto_install", true)
+
+	// How long the shim remembers the version selected for a directory,
+	// unless the files it was selected from change.
+	viper.SetDefault("shim_cache_ttl", "1h")
+
 	/* Configuration dynamic values */
 
+	// Only defaults and command line flags are known at this point.
+	if knownConfigKeys == nil {
+		knownConfigKeys = viper.AllKeys()
+	}
+
 	// Find and read the configuration file.
 	err = viper.ReadInConfig()
 
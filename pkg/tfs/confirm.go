@@ -0,0 +1,47 @@
+package tfs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/viper"
+)
+
+// ErrAborted is returned when the user declines a confirmation prompt.
+var ErrAborted = errors.New("aborted by user")
+
+// Replaced in tests.
+var (
+	confirmInput  io.Reader = os.Stdin
+	confirmOutput io.Writer = os.Stderr
+	isInteractive           = func() bool {
+		return isatty.IsTerminal(os.Stdin.Fd())
+	}
+)
+
+// confirm asks the user to confirm a destructive operation. The user is only
+// asked when stdin is a terminal and the "yes" setting is not set.
+func confirm(prompt string) error {
+	if viper.GetBool("yes") || !isInteractive() {
+		return nil
+	}
+
+	fmt.Fprintf(confirmOutput, "%s [y/N] ", prompt)
+
+	answer, err := bufio.NewReader(confirmInput).ReadString('\n')
+	if err != nil && answer == "" {
+		return ErrAborted
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return ErrAborted
+	}
+}
@@ -0,0 +1,148 @@
+package tfs
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// stubConfirm simulates a user answering the confirmation prompt on a terminal.
+func stubConfirm(t *testing.T, answer string) *bytes.Buffer {
+	t.Helper()
+
+	var prompt bytes.Buffer
+	input, output, interactive := confirmInput, confirmOutput, isInteractive
+	t.Cleanup(func() {
+		confirmInput, confirmOutput, isInteractive = input, output, interactive
+	})
+
+	confirmInput = strings.NewReader(answer)
+	confirmOutput = &prompt
+	isInteractive = func() bool { return true }
+
+	return &prompt
+}
+
+func TestPruneConfirmation(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  string
+		yes     bool
+		removed bool
+	}{
+		{"accepted", "y\n", false, true},
+		{"declined", "n\n", false, false},
+		{"no answer", "", false, false},
+		{"skipped", "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir, cleanup := initTestFS(t)
+			defer cleanup()
+
+			viper.Set("yes", tt.yes)
+			prompt := stubConfirm(t, tt.answer)
+
+			cache := newPinTestCache(t, cacheDir, "1.5.7")
+
+			_, err := cache.Prune(false)
+			if tt.removed && err != nil {
+				t.Fatalf("Cache.Prune() failed: %v", err)
+			}
+			if !tt.removed && !errors.Is(err, ErrAborted) {
+				t.Fatalf("Expected ErrAborted, got %v", err)
+			}
+
+			if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.5.7")); exists == tt.removed {
+				t.Errorf("Expected removal: %v, but file exists: %v", tt.removed, exists)
+			}
+			if asked := strings.Contains(prompt.String(), "1.5.7"); asked == tt.yes {
+				t.Errorf("Unexpected prompt %q", prompt.String())
+			}
+		})
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("dry_run", true)
+	prompt := stubConfirm(t, "n\n")
+
+	cache := newPinTestCache(t, cacheDir, "1.5.7", "1.6.0")
+
+	report, err := cache.PruneUntil(mustVersion(t, "1.6.0"), false)
+	if err != nil {
+		t.Fatalf("Cache.PruneUntil() failed: %v", err)
+	}
+
+	if !report.DryRun || len(report.Removed) != 1 || report.Removed[0].Version != "1.5.7" {
+		t.Errorf("Expected 1.5.7 to be reported, got %+v", report)
+	}
+	if report.ReclaimedBytes != uint64(len("terraform 1.5.7")) || report.CacheSize != uint64(len("terraform 1.6.0")) {
+		t.Errorf("Unexpected reclaimed space %d and cache size %d", report.ReclaimedBytes, report.CacheSize)
+	}
+	if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.5.7")); !exists {
+		t.Errorf("Expected 1.5.7 to remain in dry-run mode")
+	}
+	if prompt.Len() != 0 {
+		t.Errorf("Expected no confirmation in dry-run mode, got %q", prompt.String())
+	}
+}
+
+func TestAutoCleanDryRun(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("dry_run", true)
+	viper.Set("cache_auto_clean", true)
+	viper.Set("cache_history", 1)
+
+	cache := newPinTestCache(t, cacheDir, "1.5.7", "1.6.0", "1.7.0")
+
+	report, err := cache.AutoClean()
+	if err != nil {
+		t.Fatalf("Cache.AutoClean() failed: %v", err)
+	}
+
+	if len(report.Removed) != 2 || report.Removed[0].Version != "1.5.7" || report.Removed[1].Version != "1.6.0" {
+		t.Errorf("Expected 1.5.7 and 1.6.0 to be reported, got %+v", report.Removed)
+	}
+	for _, v := range []string{"1.5.7", "1.6.0", "1.7.0"} {
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v)); !exists {
+			t.Errorf("Expected %s to remain in dry-run mode", v)
+		}
+	}
+}
+
+func TestPruneReportsAllErrors(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := newPinTestCache(t, cacheDir, "1.5.7", "1.6.0", "1.7.0")
+
+	// Removed behind the cache's back.
+	if err := AppFs.Remove(filepath.Join(cacheDir, testFilePrefix+"1.6.0")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	report, err := cache.Prune(false)
+	if err == nil || !strings.Contains(err.Error(), "1.6.0") {
+		t.Errorf("Expected an error about 1.6.0, got %v", err)
+	}
+	if len(report.Removed) != 2 || len(report.Errors) != 1 {
+		t.Errorf("Expected the other releases to be removed, got %+v", report)
+	}
+	for _, v := range []string{"1.5.7", "1.7.0"} {
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v)); exists {
+			t.Errorf("Expected %s to be removed", v)
+		}
+	}
+}
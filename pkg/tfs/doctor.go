@@ -0,0 +1,323 @@
+package tfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Diagnostic check statuses.
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// How long to wait for the release source to answer.
+const doctorSourceTimeout = 10 * time.Second
+
+// CheckResult is the outcome of a diagnostic check.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+
+	// How to fix the problem, if any.
+	Hint string `json:"hint,omitempty"`
+}
+
+// Diagnosis holds the outcome of all diagnostic checks.
+type Diagnosis struct {
+	Checks []CheckResult `json:"checks"`
+}
+
+func (d *Diagnosis) add(name, status, message, hint string) {
+	d.Checks = append(d.Checks, CheckResult{Name: name, Status: status, Message: message, Hint: hint})
+}
+
+// Failed tells whether any check failed.
+func (d *Diagnosis) Failed() bool {
+	for _, check := range d.Checks {
+		if check.Status == CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Doctor checks the tfs installation and environment for common problems.
+func (c *LocalCache) Doctor() *Diagnosis {
+	d := &Diagnosis{}
+
+	c.checkPath(d)
+	c.checkSymlink(d)
+	c.checkCacheDirectory(d)
+	c.checkCacheContents(d)
+	checkConfig(d)
+	c.checkLock(d)
+	c.checkSource(d)
+
+	return d
+}
+
+// checkPath makes sure the user bin directory is on PATH,
+// ahead of any other terraform binary.
+func (c *LocalCache) checkPath(d *Diagnosis) {
+	const name = "path"
+
+	userBinDir := filepath.Clean(viper.GetString("user_bin_directory"))
+	hint := fmt.Sprintf("add %s at the beginning of PATH in your shell configuration", userBinDir)
+
+	if !slices.Contains(cleanPathList(os.Getenv("PATH")), userBinDir) {
+		d.add(name, CheckFail, fmt.Sprintf("%s is not on PATH", userBinDir), hint)
+		return
+	}
+
+	found, err := exec.LookPath(terraformProductName)
+	if err != nil {
+		d.add(name, CheckPass, fmt.Sprintf("%s is on PATH", userBinDir), "")
+		return
+	}
+
+	dir := filepath.Dir(found)
+	switch {
+	case dir == userBinDir:
+		d.add(name, CheckPass, fmt.Sprintf("%s is on PATH, ahead of any other terraform binary", userBinDir), "")
+	case strings.HasPrefix(dir, filepath.Join(c.directory, versionBinDirName)+string(os.PathSeparator)):
+		d.add(name, CheckPass, fmt.Sprintf("terraform is selected by 'tfs env' (%s)", os.Getenv(ActiveVersionEnv)), "")
+	default:
+		d.add(name, CheckWarn, fmt.Sprintf("%s comes before %s on PATH", found, userBinDir), hint)
+	}
+}
+
+// checkSymlink makes sure the terraform link points to a cached binary or to the shim.
+func (c *LocalCache) checkSymlink(d *Diagnosis) {
+	const name = "symlink"
+
+	symlink := filepath.Join(viper.GetString("user_bin_directory"), terraformProductName)
+
+	fi, err := os.Lstat(symlink)
+	if os.IsNotExist(err) {
+		d.add(name, CheckWarn, fmt.Sprintf("%s does not exist", symlink), "run 'tfs <version>' to activate a Terraform version")
+		return
+	}
+	if err != nil {
+		d.add(name, CheckFail, err.Error(), "")
+		return
+	}
+	if !c.isManaged(symlink, fi) {
+		d.add(name, CheckFail, fmt.Sprintf("%s is not managed by tfs", symlink), "run 'tfs --force' to back it up, or 'tfs --adopt' to add it to the cache")
+		return
+	}
+
+	target, err := filepath.EvalSymlinks(symlink)
+	if err != nil {
+		d.add(name, CheckFail, fmt.Sprintf("%s is a broken link", symlink), "run 'tfs <version>' to activate a Terraform version")
+		return
+	}
+	d.add(name, CheckPass, fmt.Sprintf("%s points to %s", symlink, target), "")
+}
+
+// checkCacheDirectory makes sure the cache directory belongs to
+// the current user, and can be written to.
+func (c *LocalCache) checkCacheDirectory(d *Diagnosis) {
+	const name = "cache-directory"
+
+	fi, err := os.Stat(c.directory)
+	if os.IsNotExist(err) {
+		d.add(name, CheckPass, fmt.Sprintf("%s will be created on first install", c.directory), "")
+		return
+	}
+	if err != nil {
+		d.add(name, CheckFail, err.Error(), "")
+		return
+	}
+	if !fi.IsDir() {
+		d.add(name, CheckFail, fmt.Sprintf("%s is not a directory", c.directory), "remove it, or set 'cache_directory' to another path")
+		return
+	}
+
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		d.add(name, CheckWarn, fmt.Sprintf("%s belongs to user %d", c.directory, st.Uid), fmt.Sprintf("run 'sudo chown -R %d %s'", os.Getuid(), c.directory))
+		return
+	}
+
+	f, err := os.CreateTemp(c.directory, ".doctor.*"+tempFileSuffix)
+	if err != nil {
+		d.add(name, CheckFail, fmt.Sprintf("%s is not writable", c.directory), fmt.Sprintf("run 'chmod u+rwx %s'", c.directory))
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	d.add(name, CheckPass, fmt.Sprintf("%s is writable (%s)", c.directory, fi.Mode().Perm()), "")
+}
+
+// checkCacheContents looks for files tfs does not know about in the cache directory.
+func (c *LocalCache) checkCacheContents(d *Diagnosis) {
+	const name = "cache-contents"
+
+	entries, err := os.ReadDir(c.directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			d.add(name, CheckPass, "cache is empty", "")
+		} else {
+			d.add(name, CheckFail, err.Error(), "")
+		}
+		return
+	}
+
+	var invalid, stray []string
+
+	prefix := viper.GetString("terraform_file_name_prefix")
+	known := []string{lockFileName, metadataFileName, remoteIndexFileName, shimCacheFileName, quarantineDirName, originalDirName, versionBinDirName}
+
+	for _, entry := range entries {
+		fileName := entry.Name()
+		switch {
+		case slices.Contains(known, fileName):
+		case strings.HasPrefix(fileName, ".") && strings.HasSuffix(fileName, tempFileSuffix):
+			// Install in progress, or removed on next run.
+		case strings.HasPrefix(fileName, prefix):
+			if _, err := versionFromFileName(strings.TrimSuffix(fileName, checksumFileSuffix)); err != nil || entry.IsDir() {
+				invalid = append(invalid, fileName)
+			}
+		default:
+			stray = append(stray, fileName)
+		}
+	}
+
+	switch {
+	case len(invalid) > 0:
+		d.add(name, CheckFail, "unparseable file names: "+strings.Join(invalid, ", "), fmt.Sprintf("remove them from %s", c.directory))
+	case len(stray) > 0:
+		d.add(name, CheckWarn, "unknown files: "+strings.Join(stray, ", "), fmt.Sprintf("remove them from %s if they are not needed", c.directory))
+	default:
+		d.add(name, CheckPass, fmt.Sprintf("%d entries, all known", len(entries)), "")
+	}
+}
+
+// checkConfig makes sure the configuration file can be parsed, and
+// only holds known settings.
+func checkConfig(d *Diagnosis) {
+	const name = "config"
+
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		d.add(name, CheckPass, "no configuration file, using default settings", "")
+		return
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			d.add(name, CheckPass, "no configuration file, using default settings", "")
+		} else {
+			d.add(name, CheckFail, err.Error(), fmt.Sprintf("fix the syntax of %s", path))
+		}
+		return
+	}
+
+	var unknown []string
+	for _, key := range v.AllKeys() {
+		if !slices.Contains(knownConfigKeys, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		d.add(name, CheckWarn, fmt.Sprintf("unknown settings in %s: %s", path, strings.Join(unknown, ", ")), "check the spelling of these settings, or remove them")
+		return
+	}
+
+	d.add(name, CheckPass, fmt.Sprintf("%s is valid", path), "")
+}
+
+// checkLock makes sure no other process is holding the cache lock.
+func (c *LocalCache) checkLock(d *Diagnosis) {
+	const name = "lock"
+
+	f, err := os.OpenFile(filepath.Join(c.directory, lockFileName), os.O_RDONLY, 0)
+	if os.IsNotExist(err) {
+		d.add(name, CheckPass, "cache is not locked", "")
+		return
+	}
+	if err != nil {
+		d.add(name, CheckFail, err.Error(), "")
+		return
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		pid := lockHolder(f)
+		d.add(name, CheckWarn, fmt.Sprintf("cache is locked by process %d", pid), fmt.Sprintf("wait for process %d to finish, or stop it if it is stuck", pid))
+		return
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	d.add(name, CheckPass, "cache is not locked", "")
+}
+
+// checkSource makes sure the release source can be reached.
+func (c *LocalCache) checkSource(d *Diagnosis) {
+	const name = "release-source"
+
+	// Bypass the local copy of the published versions list.
+	source, err := NewSource()
+	if err != nil {
+		d.add(name, CheckFail, err.Error(), "fix the release source settings")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorSourceTimeout)
+	defer cancel()
+
+	versions, err := source.Versions(ctx)
+	if err != nil {
+		d.add(name, CheckFail, fmt.Sprintf("%s: %v", source.Name(), err), "check your network and proxy settings, or use --offline")
+		return
+	}
+	d.add(name, CheckPass, fmt.Sprintf("%s lists %d versions", source.Name(), len(versions)), "")
+}
+
+// cleanPathList splits a PATH-like variable into cleaned paths.
+func cleanPathList(path string) []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(path) {
+		if dir != "" {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	return dirs
+}
+
+// WriteJSON writes the diagnosis as a JSON document.
+func (d *Diagnosis) WriteJSON(w io.Writer) error {
+	return writeJSON(w, d)
+}
+
+// WriteText writes the diagnosis in a human readable form.
+func (d *Diagnosis) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	for _, check := range d.Checks {
+		fmt.Fprintf(&b, "[%s] %-16s %s\n", check.Status, check.Name, check.Message)
+		if check.Hint != "" {
+			fmt.Fprintf(&b, "       %-16s hint: %s\n", "", check.Hint)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,122 @@
+package tfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLocalCacheDoctor(t *testing.T) {
+	_, cleanup := initTestFS(t)
+	defer cleanup()
+
+	// Checks run against the real filesystem.
+	cacheDir := t.TempDir()
+	binDir := t.TempDir()
+	viper.Set("user_bin_directory", binDir)
+	viper.Set("offline", true)
+	viper.Set("offline_source_directory", t.TempDir())
+
+	for _, name := range []string{testFilePrefix + "1.5.7", testFilePrefix + "1.5.7" + checksumFileSuffix, remoteIndexFileName} {
+		if err := os.WriteFile(filepath.Join(cacheDir, name), nil, 0644); err != nil {
+			t.Fatalf("Failed to write cache file: %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(cacheDir, testFilePrefix+"1.5.7"), filepath.Join(binDir, "terraform")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeProjectFile(t, filepath.Dir(configFile), "config.yaml", "cache_history: 3\ncache_histroy: 4\n")
+	viper.SetConfigFile(configFile)
+	knownConfigKeys = []string{"cache_history"}
+	defer func() { knownConfigKeys = nil }()
+
+	cache := NewLocalCache(cacheDir)
+
+	diagnose := func() map[string]CheckResult {
+		t.Helper()
+		checks := make(map[string]CheckResult)
+		for _, check := range cache.Doctor().Checks {
+			checks[check.Name] = check
+		}
+		return checks
+	}
+
+	t.Run("bin directory not on PATH", func(t *testing.T) {
+		t.Setenv("PATH", "/usr/bin")
+		checks := diagnose()
+
+		expected := map[string]string{
+			"path":            CheckFail,
+			"symlink":         CheckPass,
+			"cache-directory": CheckPass,
+			"cache-contents":  CheckPass,
+			"config":          CheckWarn,
+			"lock":            CheckPass,
+			"release-source":  CheckPass,
+		}
+		for name, status := range expected {
+			if checks[name].Status != status {
+				t.Errorf("expected %s check to %s, got %+v", name, status, checks[name])
+			}
+		}
+		if checks["path"].Hint == "" {
+			t.Errorf("expected a hint for the path check")
+		}
+	})
+
+	t.Run("bin directory on PATH", func(t *testing.T) {
+		t.Setenv("PATH", binDir+string(os.PathListSeparator)+"/usr/bin")
+		if check := diagnose()["path"]; check.Status != CheckPass {
+			t.Fatalf("expected path check to pass, got %+v", check)
+		}
+	})
+
+	t.Run("stray and invalid files", func(t *testing.T) {
+		writeProjectFile(t, cacheDir, "notes.txt", "")
+		if check := diagnose()["cache-contents"]; check.Status != CheckWarn {
+			t.Fatalf("expected cache contents check to warn, got %+v", check)
+		}
+
+		writeProjectFile(t, cacheDir, testFilePrefix+"latest", "")
+		if check := diagnose()["cache-contents"]; check.Status != CheckFail {
+			t.Fatalf("expected cache contents check to fail, got %+v", check)
+		}
+	})
+
+	t.Run("broken symlink", func(t *testing.T) {
+		os.Remove(filepath.Join(cacheDir, testFilePrefix+"1.5.7"))
+		if check := diagnose()["symlink"]; check.Status != CheckFail {
+			t.Fatalf("expected symlink check to fail, got %+v", check)
+		}
+	})
+
+	t.Run("lock contention", func(t *testing.T) {
+		other := NewLocalCache(cacheDir)
+		unlock, err := other.Lock()
+		if err != nil {
+			t.Fatalf("Lock() failed: %v", err)
+		}
+		defer unlock()
+
+		if check := diagnose()["lock"]; check.Status != CheckWarn {
+			t.Fatalf("expected lock check to warn, got %+v", check)
+		}
+	})
+
+	t.Run("JSON output", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := cache.Doctor().WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON() failed: %v", err)
+		}
+		var decoded Diagnosis
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Checks) != 7 {
+			t.Fatalf("unexpected JSON output: %s", buf.String())
+		}
+	})
+}
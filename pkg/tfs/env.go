@@ -0,0 +1,188 @@
+package tfs
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Environment variable holding the version selected by "tfs env".
+	ActiveVersionEnv = "TFS_ACTIVE_VERSION"
+
+	// Name of the cache subdirectory holding one directory per version,
+	// each of them holding a terraform link to the cached binary.
+	versionBinDirName = "versions"
+)
+
+// Supported shells.
+var Shells = []string{"bash", "zsh", "fish"}
+
+// DefaultShell returns the user login shell if supported, or bash.
+func DefaultShell() string {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	for _, s := range Shells {
+		if s == shell {
+			return s
+		}
+	}
+	return "bash"
+}
+
+// Env returns shell code that prepends the directory of the selected
+// Terraform version to PATH, and sets the TFS_ACTIVE_VERSION variable. The
+// expr parameter is a version or a constraint; the version required by the
+// project is used when it is empty. When no version is required, the code
+// restores PATH so that the globally active version is used.
+func (c *LocalCache) Env(expr, shell string) (string, error) {
+	if err := checkShell(shell); err != nil {
+		return "", err
+	}
+
+	sel, err := c.SelectVersion(expr)
+	if err != nil {
+		return "", err
+	}
+
+	var dir, active string
+
+	if sel != nil {
+		r, err := c.InstallSelection(sel)
+		if err != nil {
+			return "", err
+		}
+		if dir, err = r.linkVersionBinDir(); err != nil {
+			return "", err
+		}
+		r.recordUse()
+		active = r.Version.String()
+	}
+
+	// Drop the directories set by a previous evaluation.
+	path := make([]string, 0)
+	if dir != "" {
+		path = append(path, dir)
+	}
+	prefix := filepath.Join(c.directory, versionBinDirName) + string(os.PathSeparator)
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if !strings.HasPrefix(entry, prefix) {
+			path = append(path, entry)
+		}
+	}
+
+	var b strings.Builder
+
+	switch shell {
+	case "fish":
+		quoted := make([]string, len(path))
+		for i, entry := range path {
+			quoted[i] = fishQuote(entry)
+		}
+		fmt.Fprintf(&b, "set -gx PATH %s;\n", strings.Join(quoted, " "))
+		if active != "" {
+			fmt.Fprintf(&b, "set -gx %s %s;\n", ActiveVersionEnv, fishQuote(active))
+		} else {
+			fmt.Fprintf(&b, "set -e %s;\n", ActiveVersionEnv)
+		}
+	default:
+		fmt.Fprintf(&b, "export PATH=%s;\n", shQuote(strings.Join(path, string(os.PathListSeparator))))
+		if active != "" {
+			fmt.Fprintf(&b, "export %s=%s;\n", ActiveVersionEnv, shQuote(active))
+		} else {
+			fmt.Fprintf(&b, "unset %s;\n", ActiveVersionEnv)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// Hook returns shell code that evaluates the output of "tfs env" each
+// time the working directory changes. The executable parameter is the
+// path of the tfs executable.
+func Hook(shell, executable string) (string, error) {
+	if err := checkShell(shell); err != nil {
+		return "", err
+	}
+
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(`_tfs_hook() {
+  local previous_exit_status=$?
+  if [[ "$PWD" != "${_TFS_LAST_PWD:-}" ]]; then
+    _TFS_LAST_PWD="$PWD"
+    eval "$(%[1]s env --shell bash)"
+  fi
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_tfs_hook;"* ]]; then
+  PROMPT_COMMAND="_tfs_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`, shQuote(executable)), nil
+	case "zsh":
+		return fmt.Sprintf(`_tfs_hook() {
+  eval "$(%[1]s env --shell zsh)"
+}
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_tfs_hook]} )); then
+  chpwd_functions=(_tfs_hook $chpwd_functions)
+fi
+_tfs_hook
+`, shQuote(executable)), nil
+	default:
+		return fmt.Sprintf(`function _tfs_hook --on-variable PWD
+  %[1]s env --shell fish | source
+end
+_tfs_hook
+`, fishQuote(executable)), nil
+	}
+}
+
+// checkShell makes sure the shell is supported.
+func checkShell(shell string) error {
+	for _, s := range Shells {
+		if s == shell {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported shell %q, expected one of %s", shell, strings.Join(Shells, ", "))
+}
+
+// versionBinDir returns the directory holding a terraform link to the release.
+func (r *release) versionBinDir() string {
+	return filepath.Join(r.parentCache.directory, versionBinDirName, r.Version.String())
+}
+
+// linkVersionBinDir creates the directory holding a terraform
+// link to the release, and returns its path.
+func (r *release) linkVersionBinDir() (string, error) {
+	var (
+		dir     = r.versionBinDir()
+		symlink = filepath.Join(dir, terraformProductName)
+	)
+
+	if target, ok, _ := AppFs.ReadlinkIfPossible(symlink); ok && target == r.path() {
+		return dir, nil
+	}
+
+	// Replace any stale link.
+	AppFs.Remove(symlink)
+
+	if err := AppFs.SymlinkIfPossible(r.path(), symlink); err != nil {
+		slog.Error("Failed to create symlink", "error", err, "target", r.path(), "symlink", symlink)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// shQuote quotes a string for POSIX shells.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes a string for the fish shell.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,91 @@
+package tfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLocalCacheEnv(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv(SourceEnvironment, "")
+	viper.Set("version_search_boundary", dir)
+
+	versionDir := filepath.Join(cacheDir, versionBinDirName, "1.5.7")
+	t.Setenv("PATH", strings.Join([]string{filepath.Join(cacheDir, versionBinDirName, "1.4.0"), "/usr/bin"}, string(os.PathListSeparator)))
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.5.7"))
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		shell    string
+		expected string
+	}{
+		{
+			name:     "bash",
+			expr:     "1.5.7",
+			shell:    "bash",
+			expected: "export PATH='" + versionDir + ":/usr/bin';\nexport TFS_ACTIVE_VERSION='1.5.7';\n",
+		},
+		{
+			name:     "fish",
+			expr:     "~> 1.5",
+			shell:    "fish",
+			expected: "set -gx PATH '" + versionDir + "' '/usr/bin';\nset -gx TFS_ACTIVE_VERSION '1.5.7';\n",
+		},
+		{
+			name:     "no requirement",
+			shell:    "zsh",
+			expected: "export PATH='/usr/bin';\nunset TFS_ACTIVE_VERSION;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := cache.Env(tt.expr, tt.shell)
+			if err != nil {
+				t.Fatalf("Env() failed: %v", err)
+			}
+			if code != tt.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tt.expected, code)
+			}
+		})
+	}
+
+	target, err := os.Readlink(filepath.Join(versionDir, "terraform"))
+	if err != nil || target != filepath.Join(cacheDir, testFilePrefix+"1.5.7") {
+		t.Fatalf("unexpected version directory link: %q (%v)", target, err)
+	}
+
+	if _, err := cache.Env("", "powershell"); err == nil {
+		t.Fatal("expected error for unsupported shell, got nil")
+	}
+}
+
+func TestHook(t *testing.T) {
+	for _, shell := range Shells {
+		code, err := Hook(shell, "/opt/my tools/tfs")
+		if err != nil {
+			t.Fatalf("Hook(%s) failed: %v", shell, err)
+		}
+		if !strings.Contains(code, "'/opt/my tools/tfs' env --shell "+shell) {
+			t.Fatalf("unexpected %s hook:\n%s", shell, code)
+		}
+	}
+
+	if _, err := Hook("tcsh", "tfs"); err == nil {
+		t.Fatal("expected error for unsupported shell, got nil")
+	}
+}
@@ -0,0 +1,31 @@
+package tfs
+
+import (
+	"errors"
+)
+
+// Exec runs the selected Terraform version with the given arguments, in
+// place of the current process, installing it first if needed. The expr
+// parameter is a version or a constraint; the version required by the
+// project is used when it is empty. The active release is left untouched.
+func (c *LocalCache) Exec(expr string, args []string) error {
+	sel, err := c.SelectVersion(expr)
+	if err != nil {
+		return err
+	}
+	if sel == nil {
+		return errors.New("no Terraform version requirement found; give a version or a constraint")
+	}
+
+	r, err := c.InstallSelection(sel)
+	if err != nil {
+		return err
+	}
+
+	// Never run a binary that does not match its recorded checksum.
+	if err := r.VerifyChecksum(); err != nil {
+		return err
+	}
+
+	return r.exec(args)
+}
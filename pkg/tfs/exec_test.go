@@ -0,0 +1,57 @@
+package tfs
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLocalCacheExec(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	calls := stubExec(t)
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv(SourceEnvironment, "")
+	viper.Set("version_search_boundary", dir)
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.5.7", "1.6.3"))
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	t.Run("no requirement", func(t *testing.T) {
+		if err := cache.Exec("", []string{"version"}); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("constraint", func(t *testing.T) {
+		if err := cache.Exec("~> 1.5.0", []string{"plan", "-input=false"}); err != nil {
+			t.Fatalf("Exec() failed: %v", err)
+		}
+		expected := []string{filepath.Join(cacheDir, testFilePrefix+"1.5.7"), "terraform", "plan", "-input=false"}
+		if !reflect.DeepEqual((*calls)[0], expected) {
+			t.Fatalf("expected %v, got %v", expected, (*calls)[0])
+		}
+		if cache.activeRelease != nil {
+			t.Fatalf("expected active release to be untouched, got %s", cache.activeRelease.Version)
+		}
+	})
+
+	t.Run("project requirement", func(t *testing.T) {
+		writeProjectFile(t, dir, ".terraform-version", "1.6.3")
+
+		if err := cache.Exec("", nil); err != nil {
+			t.Fatalf("Exec() failed: %v", err)
+		}
+		if path := (*calls)[1][0]; path != filepath.Join(cacheDir, testFilePrefix+"1.6.3") {
+			t.Fatalf("expected 1.6.3 to be run, got %s", path)
+		}
+	})
+}
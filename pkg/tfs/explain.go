@@ -0,0 +1,303 @@
+package tfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/viper"
+)
+
+// Sources reported by Explain, in addition to the version requirement ones.
+const (
+	SourceArgument    = "argument"
+	SourceProjectLock = ProjectLockFileName
+)
+
+// Reasons why a version was selected.
+const (
+	ReasonArgument     = "command line argument"
+	ReasonProjectLock  = "lock file"
+	ReasonRequirement  = "version requirement"
+	ReasonLastRelease  = "most recent cached release"
+	ReasonNoCandidates = "no version requirement and empty cache"
+)
+
+// Explanation describes how the Terraform version of a project is selected.
+type Explanation struct {
+	// Every source of version requirement found, in order of precedence.
+	Sources []ExplainedSource `json:"sources"`
+
+	// Version resolution strategy.
+	Strategy string `json:"strategy"`
+
+	// Constraint the version must satisfy.
+	Constraint string `json:"constraint,omitempty"`
+
+	// Cached and published versions satisfying the constraint.
+	CachedCandidates []string `json:"cachedCandidates"`
+	RemoteCandidates []string `json:"remoteCandidates"`
+
+	// Why published versions could not be listed, if so.
+	RemoteError string `json:"remoteError,omitempty"`
+
+	// Selected version, and why it was selected.
+	Version string `json:"version,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+
+	// Why no version could be selected, if so.
+	Error string `json:"error,omitempty"`
+}
+
+// ExplainedSource is a version requirement found while looking for one.
+type ExplainedSource struct {
+	Kind       string `json:"kind"`
+	Expression string `json:"expression"`
+	File       string `json:"file,omitempty"`
+	Line       int    `json:"line,omitempty"`
+
+	// Whether the source takes part in the version selection.
+	Used bool `json:"used"`
+}
+
+// Explain goes through the same steps as the root command to select a
+// Terraform version, without installing or activating anything, and reports
+// every source consulted along the way. The arg parameter is the version or
+// constraint given on the command line, if any.
+//
+// When no version can be selected, the returned explanation holds the error.
+func (c *LocalCache) Explain(arg string) (*Explanation, error) {
+	e := &Explanation{
+		Sources:          []ExplainedSource{},
+		Strategy:         viper.GetString("version_resolution"),
+		CachedCandidates: []string{},
+		RemoteCandidates: []string{},
+	}
+	if e.Strategy == "" {
+		e.Strategy = ResolveCachedFirst
+	}
+
+	if err := e.collectSources(arg); err != nil {
+		return nil, err
+	}
+
+	v, err := e.selectVersion(c, arg)
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if v != nil {
+		e.Version = v.String()
+	}
+
+	// Candidates.
+	match := candidateFilter(e.Constraint)
+
+	for _, v := range sortedVersions(c.CachedVersions()) {
+		if match(v) {
+			e.CachedCandidates = append(e.CachedCandidates, v.String())
+		}
+	}
+	// Published releases are only looked at to satisfy a constraint.
+	if e.Strategy != ResolveCachedOnly && e.Constraint != "" {
+		remote, err := c.RemoteVersions()
+		if err != nil {
+			e.RemoteError = err.Error()
+		}
+		for _, v := range sortedVersions(remote) {
+			if match(v) {
+				e.RemoteCandidates = append(e.RemoteCandidates, v.String())
+			}
+		}
+	}
+
+	return e, err
+}
+
+// collectSources lists all the version requirements that could apply
+// to the current directory, the way FindVersionRequirement and
+// FindProjectLock look for them.
+func (e *Explanation) collectSources(arg string) error {
+	if arg != "" {
+		e.Sources = append(e.Sources, ExplainedSource{Kind: SourceArgument, Expression: arg})
+	}
+	if expr := strings.TrimSpace(os.Getenv(SourceEnvironment)); expr != "" {
+		e.Sources = append(e.Sources, ExplainedSource{Kind: SourceEnvironment, Expression: expr})
+	}
+
+	path, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range searchDirectories(path) {
+		for _, read := range []func(string) (*Requirement, error){
+			readTerraformVersionFile,
+			readToolVersionsFile,
+		} {
+			req, err := read(dir)
+			if err != nil {
+				return err
+			}
+			if req != nil {
+				e.Sources = append(e.Sources, ExplainedSource{Kind: req.Source, Expression: req.Expression, File: req.File})
+			}
+		}
+
+		constraints, err := moduleVersionConstraints(dir)
+		if err != nil {
+			return err
+		}
+		for _, vc := range constraints {
+			e.Sources = append(e.Sources, ExplainedSource{
+				Kind:       SourceRequiredVersion,
+				Expression: vc.Constraint,
+				File:       vc.File,
+				Line:       vc.Line,
+			})
+		}
+
+		lock, err := ReadProjectLock(filepath.Join(dir, ProjectLockFileName))
+		if err != nil {
+			return err
+		}
+		if lock != nil {
+			e.Sources = append(e.Sources, ExplainedSource{Kind: SourceProjectLock, Expression: lock.Version, File: lock.Path()})
+		}
+	}
+
+	return nil
+}
+
+// selectVersion selects the version the same way the root command does,
+// and flags the sources it relies on.
+func (e *Explanation) selectVersion(c *LocalCache, arg string) (*version.Version, error) {
+	if arg != "" {
+		e.use(SourceArgument, "")
+		e.Constraint = arg
+		e.Reason = ReasonArgument
+	}
+
+	sel, err := c.SelectVersion(arg)
+	if sel != nil {
+		if req := sel.Requirement; req != nil {
+			e.Constraint = req.Expression
+			e.Reason = ReasonRequirement
+			e.use(req.Source, req.File)
+			for _, vc := range req.Constraints {
+				e.use(req.Source, vc.File)
+			}
+		}
+		if sel.Lock != nil {
+			e.Reason = ReasonProjectLock
+			e.use(SourceProjectLock, sel.Lock.Path())
+		}
+		return sel.Version, err
+	}
+	if err != nil || arg != "" {
+		return nil, err
+	}
+
+	if !c.IsEmpty() {
+		e.Reason = ReasonLastRelease
+		return c.LastRelease.Version, nil
+	}
+	e.Reason = ReasonNoCandidates
+	return nil, nil
+}
+
+// use flags the sources of the given kind read from the given file.
+func (e *Explanation) use(kind, file string) {
+	for i := range e.Sources {
+		if e.Sources[i].Kind == kind && e.Sources[i].File == file {
+			e.Sources[i].Used = true
+		}
+	}
+}
+
+// candidateFilter returns a function telling whether
+// a version is a candidate for the given expression.
+func candidateFilter(expr string) func(*version.Version) bool {
+	if expr == "" {
+		return func(*version.Version) bool { return true }
+	}
+	if expr == "latest" {
+		return func(v *version.Version) bool { return v.Prerelease() == "" }
+	}
+	if raw, ok := strings.CutPrefix(expr, "latest:"); ok {
+		pattern, err := regexp.Compile(raw)
+		if err != nil {
+			return func(*version.Version) bool { return false }
+		}
+		return func(v *version.Version) bool { return pattern.MatchString(v.String()) }
+	}
+
+	constraint, err := version.NewConstraint(expr)
+	if err != nil {
+		return func(*version.Version) bool { return false }
+	}
+	return constraint.Check
+}
+
+// sortedVersions returns the given versions in ascending order, without duplicates.
+func sortedVersions(versions []*version.Version) []*version.Version {
+	sorted := slices.Clone(versions)
+	sort.Sort(version.Collection(sorted))
+	return slices.CompactFunc(sorted, func(a, b *version.Version) bool { return a.Equal(b) })
+}
+
+// WriteJSON writes the explanation as a JSON document.
+func (e *Explanation) WriteJSON(w io.Writer) error {
+	return writeJSON(w, e)
+}
+
+// WriteText writes the explanation in a human readable form.
+func (e *Explanation) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("Sources:\n")
+	if len(e.Sources) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, s := range e.Sources {
+		mark := " "
+		if s.Used {
+			mark = "*"
+		}
+		location := s.File
+		if s.Line > 0 {
+			location = fmt.Sprintf("%s:%d", s.File, s.Line)
+		}
+		fmt.Fprintf(&b, "  %s %-22s %-16s %s\n", mark, s.Kind, s.Expression, location)
+	}
+
+	fmt.Fprintf(&b, "Strategy:          %s\n", e.Strategy)
+	fmt.Fprintf(&b, "Constraint:        %s\n", valueOrNone(e.Constraint))
+	fmt.Fprintf(&b, "Cached candidates: %s\n", valueOrNone(strings.Join(e.CachedCandidates, ", ")))
+	if e.RemoteError != "" {
+		fmt.Fprintf(&b, "Remote candidates: unavailable (%s)\n", e.RemoteError)
+	} else if e.Strategy != ResolveCachedOnly {
+		fmt.Fprintf(&b, "Remote candidates: %s\n", valueOrNone(strings.Join(e.RemoteCandidates, ", ")))
+	}
+
+	if e.Error != "" {
+		fmt.Fprintf(&b, "Version:           none (%s)\n", e.Error)
+	} else {
+		fmt.Fprintf(&b, "Version:           %s (%s)\n", valueOrNone(e.Version), e.Reason)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,106 @@
+package tfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLocalCacheExplain(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.5.2"), []byte("dummy content"))
+
+	cache := NewLocalCache(cacheDir)
+	source := newFakeSource("1.5.2", "1.5.7", "1.6.3")
+	cache.SetSource(source)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "versions.tf", `terraform { required_version = "~> 1.5.0" }`)
+	writeProjectFile(t, dir, ".tool-versions", "terraform 1.6.3")
+
+	t.Chdir(dir)
+	t.Setenv(SourceEnvironment, "")
+	viper.Set("version_search_boundary", dir)
+
+	t.Run("version requirement", func(t *testing.T) {
+		e, err := cache.Explain("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if e.Version != "1.6.3" || e.Reason != ReasonRequirement || e.Constraint != "1.6.3" {
+			t.Fatalf("unexpected explanation: %+v", e)
+		}
+		expected := []ExplainedSource{
+			{Kind: SourceToolVersionsFile, Expression: "1.6.3", File: filepath.Join(dir, ".tool-versions"), Used: true},
+			{Kind: SourceRequiredVersion, Expression: "~> 1.5.0", File: filepath.Join(dir, "versions.tf"), Line: 1},
+		}
+		if !reflect.DeepEqual(e.Sources, expected) {
+			t.Fatalf("expected sources %+v, got %+v", expected, e.Sources)
+		}
+		if !reflect.DeepEqual(e.RemoteCandidates, []string{"1.6.3"}) || len(e.CachedCandidates) != 0 {
+			t.Fatalf("unexpected candidates: %v %v", e.CachedCandidates, e.RemoteCandidates)
+		}
+	})
+
+	t.Run("command line argument", func(t *testing.T) {
+		e, err := cache.Explain("~> 1.5.0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if e.Version != "1.5.2" || e.Reason != ReasonArgument || !e.Sources[0].Used || e.Sources[1].Used {
+			t.Fatalf("unexpected explanation: %+v", e)
+		}
+		if !reflect.DeepEqual(e.CachedCandidates, []string{"1.5.2"}) || !reflect.DeepEqual(e.RemoteCandidates, []string{"1.5.2", "1.5.7"}) {
+			t.Fatalf("unexpected candidates: %v %v", e.CachedCandidates, e.RemoteCandidates)
+		}
+	})
+
+	t.Run("lock file", func(t *testing.T) {
+		writeProjectFile(t, dir, ProjectLockFileName, `{"version": "1.6.3", "hashes": {}}`)
+
+		e, err := cache.Explain("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if e.Version != "1.6.3" || e.Reason != ReasonProjectLock || !e.Sources[len(e.Sources)-1].Used {
+			t.Fatalf("unexpected explanation: %+v", e)
+		}
+	})
+
+	t.Run("no matching version", func(t *testing.T) {
+		e, err := cache.Explain("~> 2.0")
+		if err == nil || e == nil || e.Error == "" || e.Version != "" {
+			t.Fatalf("expected an explanation holding the error, got %+v (%v)", e, err)
+		}
+
+		var buf bytes.Buffer
+		if err := e.WriteText(&buf); err != nil {
+			t.Fatalf("WriteText() failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "Version:           none") {
+			t.Fatalf("unexpected text output:\n%s", buf.String())
+		}
+
+		buf.Reset()
+		if err := e.WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON() failed: %v", err)
+		}
+		var decoded Explanation
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		if decoded.Constraint != "~> 2.0" {
+			t.Fatalf("unexpected JSON output:\n%s", buf.String())
+		}
+	})
+}
@@ -16,15 +16,19 @@ func (a *AferoFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
 		fileInfo, _, err := lstater.LstatIfPossible(name)
 		return fileInfo, true, err
 	}
-	return nil, false, nil
+	fileInfo, err := a.Fs.Stat(name)
+	return fileInfo, false, err
 }
 
 func (a *AferoFs) SymlinkIfPossible(target, symlink string) error {
 	// Ensure the parent directory for the symlink exists.
-	if err := os.MkdirAll(filepath.Dir(symlink), 0755); err != nil {
+	if err := a.Fs.MkdirAll(filepath.Dir(symlink), 0755); err != nil {
 		return err
 	}
-	return os.Symlink(target, symlink)
+	if linker, ok := a.Fs.(afero.Linker); ok {
+		return linker.SymlinkIfPossible(target, symlink)
+	}
+	return &os.LinkError{Op: "symlink", Old: target, New: symlink, Err: afero.ErrNoSymlink}
 }
 
 func (a *AferoFs) EvalSymlinksIfPossible(path string) (string, bool, error) {
@@ -35,5 +39,43 @@ func (a *AferoFs) EvalSymlinksIfPossible(path string) (string, bool, error) {
 	return resolved, true, nil
 }
 
+func (a *AferoFs) ReadlinkIfPossible(name string) (string, bool, error) {
+	if reader, ok := a.Fs.(afero.LinkReader); ok {
+		target, err := reader.ReadlinkIfPossible(name)
+		return target, true, err
+	}
+	return "", false, &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+}
+
+// Temporary files in the cache directory are of the form .<name>.<random><suffix>.
+const tempFileSuffix = ".tmp"
+
+// writeFileAtomic writes data to a temporary file in the same directory
+// and renames it into place, so that readers never see a partial file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := afero.TempFile(AppFs, filepath.Dir(path), "."+filepath.Base(path)+".*"+tempFileSuffix)
+	if err != nil {
+		return err
+	}
+	defer AppFs.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := AppFs.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return AppFs.Rename(tmp.Name(), path)
+}
+
 // Default to real filesystem.
 var AppFs = &AferoFs{afero.NewOsFs()}
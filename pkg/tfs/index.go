@@ -0,0 +1,112 @@
+package tfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/afero"
+)
+
+// Name of the file holding the list of published releases in the cache directory.
+const remoteIndexFileName = "releases-index.json"
+
+var errIndexSourceMismatch = errors.New("release index belongs to another source")
+
+// remoteIndex is the on-disk copy of the versions published by a release source.
+type remoteIndex struct {
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Versions  []string  `json:"versions"`
+}
+
+// indexCachedSource keeps the list of published versions on disk for a while,
+// so that repeated lookups are fast and still work briefly offline.
+type indexCachedSource struct {
+	Source
+	path string
+	ttl  time.Duration
+}
+
+// Versions returns the cached list of published versions if it is recent
+// enough, and queries the underlying source otherwise. A stale list is
+// still used when the source cannot be reached.
+func (s *indexCachedSource) Versions(ctx context.Context) ([]*version.Version, error) {
+	logger := slog.With("source", s.Name(), "fileName", s.path)
+
+	index, err := s.read()
+	if err == nil && time.Since(index.FetchedAt) < s.ttl {
+		return index.versions(), nil
+	}
+
+	versions, fetchErr := s.Source.Versions(ctx)
+	if fetchErr != nil {
+		if err == nil {
+			logger.Warn("Using outdated list of published releases", "error", fetchErr, "fetchedAt", index.FetchedAt)
+			return index.versions(), nil
+		}
+		return nil, fetchErr
+	}
+
+	if err := s.write(versions); err != nil {
+		// Not a big deal, we will query the source again next time.
+		logger.Warn("Failed to save the list of published releases", "error", err)
+	}
+
+	return versions, nil
+}
+
+func (s *indexCachedSource) read() (*remoteIndex, error) {
+	b, err := afero.ReadFile(AppFs, s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &remoteIndex{}
+	if err := json.Unmarshal(b, index); err != nil {
+		return nil, err
+	}
+	if index.Source != s.Name() {
+		return nil, errIndexSourceMismatch
+	}
+
+	return index, nil
+}
+
+func (s *indexCachedSource) write(versions []*version.Version) error {
+	index := &remoteIndex{
+		Source:    s.Name(),
+		FetchedAt: time.Now(),
+		Versions:  make([]string, 0, len(versions)),
+	}
+	for _, v := range versions {
+		index.Versions = append(index.Versions, v.Original())
+	}
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	if err := AppFs.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, b, 0644)
+}
+
+// versions parses the versions recorded in the index.
+func (i *remoteIndex) versions() []*version.Version {
+	versions := make([]*version.Version, 0, len(i.Versions))
+	for _, raw := range i.Versions {
+		if v, err := version.NewVersion(raw); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
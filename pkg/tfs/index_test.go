@@ -0,0 +1,96 @@
+package tfs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestIndexCachedSource(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	fake := newFakeSource("1.5.7", "1.6.0")
+	s := &indexCachedSource{
+		Source: fake,
+		path:   filepath.Join(cacheDir, remoteIndexFileName),
+		ttl:    time.Hour,
+	}
+
+	for range 2 {
+		versions, err := s.Versions(context.Background())
+		if err != nil {
+			t.Fatalf("Versions() failed: %v", err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("expected 2 versions, got %d", len(versions))
+		}
+	}
+
+	if fake.listed != 1 {
+		t.Errorf("expected the source to be queried once, got %d", fake.listed)
+	}
+	if exists, _ := afero.Exists(AppFs, s.path); !exists {
+		t.Errorf("expected index file %s to be written", s.path)
+	}
+}
+
+func TestIndexCachedSourceExpired(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	fake := newFakeSource("1.5.7", "1.6.0")
+	s := &indexCachedSource{
+		Source: fake,
+		path:   filepath.Join(cacheDir, remoteIndexFileName),
+		ttl:    -time.Second, // always expired
+	}
+
+	if _, err := s.Versions(context.Background()); err != nil {
+		t.Fatalf("Versions() failed: %v", err)
+	}
+
+	t.Run("refreshed when the source is reachable", func(t *testing.T) {
+		if _, err := s.Versions(context.Background()); err != nil {
+			t.Fatalf("Versions() failed: %v", err)
+		}
+		if fake.listed != 2 {
+			t.Errorf("expected the source to be queried twice, got %d", fake.listed)
+		}
+	})
+
+	t.Run("outdated index used when the source is unreachable", func(t *testing.T) {
+		fake.err = errors.New("network is unreachable")
+
+		versions, err := s.Versions(context.Background())
+		if err != nil {
+			t.Fatalf("Versions() failed: %v", err)
+		}
+		if len(versions) != 2 {
+			t.Errorf("expected 2 versions, got %d", len(versions))
+		}
+	})
+}
+
+func TestIndexCachedSourceOtherSource(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	path := filepath.Join(cacheDir, remoteIndexFileName)
+	writeTestFile(t, path, []byte(`{"source":"elsewhere","fetchedAt":"2999-01-01T00:00:00Z","versions":["0.1.0"]}`))
+
+	fake := newFakeSource("1.5.7")
+	s := &indexCachedSource{Source: fake, path: path, ttl: time.Hour}
+
+	versions, err := s.Versions(context.Background())
+	if err != nil {
+		t.Fatalf("Versions() failed: %v", err)
+	}
+	if len(versions) != 1 || versions[0].String() != "1.5.7" {
+		t.Errorf("expected versions from the configured source, got %v", versions)
+	}
+}
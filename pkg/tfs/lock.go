@@ -0,0 +1,121 @@
+package tfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// Name of the lock file in the cache directory.
+	lockFileName = ".lock"
+
+	// Delay between two attempts to take the cache lock.
+	lockRetryInterval = 100 * time.Millisecond
+)
+
+// ErrCacheLocked is returned when the cache lock could not
+// be taken before the configured timeout.
+var ErrCacheLocked = errors.New("cache is locked")
+
+// Lock takes an advisory lock on the cache directory, so that concurrent
+// tfs processes do not step on each other's toes. The lock is reentrant
+// within a process, and must be released by calling the returned function.
+func (c *LocalCache) Lock() (func(), error) {
+	return c.lock(viper.GetDuration("cache_lock_timeout"))
+}
+
+// TryLock takes the cache lock like Lock, but returns ErrCacheLocked
+// right away if another process is holding it.
+func (c *LocalCache) TryLock() (func(), error) {
+	return c.lock(0)
+}
+
+// lock takes the cache lock, waiting up to the given timeout.
+func (c *LocalCache) lock(timeout time.Duration) (func(), error) {
+	if c.lockDepth > 0 {
+		c.lockDepth++
+		return c.unlock, nil
+	}
+
+	path := filepath.Join(c.directory, lockFileName)
+	logger := slog.With("cacheDirectory", c.directory)
+
+	// File locks only make sense on the real filesystem.
+	if err := os.MkdirAll(c.directory, os.ModePerm); err != nil {
+		logger.Error("Failed to create cache directory", "error", err)
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		logger.Error("Failed to open cache lock file", "error", err)
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	waiting := false
+
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			logger.Error("Failed to lock cache directory", "error", err)
+			return nil, err
+		}
+
+		pid := lockHolder(f)
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w: cache directory %s is held by process %d", ErrCacheLocked, c.directory, pid)
+		}
+		if !waiting {
+			logger.Info("Waiting for another tfs process to release the cache", "pid", pid)
+			waiting = true
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	// Let other processes know who is holding the lock.
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+
+	c.lockFile = f
+	c.lockDepth = 1
+
+	return c.unlock, nil
+}
+
+// unlock releases the cache lock once all the holders are done.
+func (c *LocalCache) unlock() {
+	c.lockDepth--
+	if c.lockDepth > 0 {
+		return
+	}
+
+	c.lockFile.Truncate(0)
+	syscall.Flock(int(c.lockFile.Fd()), syscall.LOCK_UN)
+	c.lockFile.Close()
+	c.lockFile = nil
+}
+
+// lockHolder returns the PID recorded in the lock file, or 0 if unknown.
+func lockHolder(f *os.File) int {
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, 32))
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid
+}
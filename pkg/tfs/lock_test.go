@@ -0,0 +1,162 @@
+package tfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestCacheLockContention(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_lock_timeout", 200*time.Millisecond)
+
+	holder := NewLocalCache(cacheDir)
+	unlock, err := holder.Lock()
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+
+	// Another cache instance opens the lock file on its own,
+	// just like another tfs process would.
+	other := NewLocalCache(cacheDir)
+
+	start := time.Now()
+	_, err = other.Lock()
+	if !errors.Is(err, ErrCacheLocked) {
+		t.Fatalf("Expected cache to be locked, got %v", err)
+	}
+	if time.Since(start) < 200*time.Millisecond {
+		t.Errorf("Expected Lock() to wait for the configured timeout")
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("process %d", os.Getpid())) {
+		t.Errorf("Expected error to name the holding process, got %q", err)
+	}
+
+	unlock()
+
+	unlockOther, err := other.Lock()
+	if err != nil {
+		t.Fatalf("Lock() failed after release: %v", err)
+	}
+	unlockOther()
+}
+
+func TestCacheLockReentrant(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.9.0"), []byte("dummy content"))
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	unlock, err := cache.Lock()
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+
+	// Prune removes releases, each removal taking the lock again.
+	if _, err := cache.Prune(false); err != nil {
+		t.Fatalf("Cache.Prune() failed while holding the lock: %v", err)
+	}
+
+	unlock()
+
+	if cache.lockFile != nil {
+		t.Errorf("Expected lock to be released")
+	}
+}
+
+func TestRecordUseSkippedWhenLocked(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_lock_timeout", time.Minute)
+
+	cache := newPinTestCache(t, cacheDir, "1.9.0")
+
+	holder := NewLocalCache(cacheDir)
+	unlock, err := holder.Lock()
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+
+	start := time.Now()
+	cache.releases["1.9.0"].recordUse()
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected recordUse() not to wait for the cache lock")
+	}
+
+	unlock()
+
+	if rm := cache.releases["1.9.0"].metadata(); rm.LastUsed != nil {
+		t.Errorf("Expected last use update to be skipped, got %v", rm.LastUsed)
+	}
+
+	cache.releases["1.9.0"].recordUse()
+	if rm := cache.releases["1.9.0"].metadata(); rm.LastUsed == nil {
+		t.Errorf("Expected last use to be recorded once the lock is released")
+	}
+}
+
+func TestInstallCachedReleaseWithoutLock(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_lock_timeout", 200*time.Millisecond)
+
+	cache := newPinTestCache(t, cacheDir, "1.9.0")
+
+	holder := NewLocalCache(cacheDir)
+	unlock, err := holder.Lock()
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	defer unlock()
+
+	if err := cache.releases["1.9.0"].Install(); err != nil {
+		t.Fatalf("Expected cached release to be installed without the lock, got %v", err)
+	}
+	if err := cache.NewRelease(mustVersion(t, "1.10.0")).Install(); !errors.Is(err, ErrCacheLocked) {
+		t.Errorf("Expected download to wait for the lock, got %v", err)
+	}
+}
+
+func TestInstallSelectionWithoutLock(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_lock_timeout", time.Minute)
+
+	cache := newPinTestCache(t, cacheDir, "1.9.0")
+
+	holder := NewLocalCache(cacheDir)
+	unlock, err := holder.Lock()
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	defer unlock()
+
+	sel := &Selection{
+		Version:     mustVersion(t, "1.9.0"),
+		Requirement: &Requirement{Expression: "1.9.0", Directory: t.TempDir()},
+	}
+
+	start := time.Now()
+	if _, err := cache.InstallSelection(sel); err != nil {
+		t.Fatalf("InstallSelection() failed: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected InstallSelection() not to wait for the cache lock")
+	}
+}
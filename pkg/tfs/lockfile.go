@@ -0,0 +1,223 @@
+package tfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/viper"
+)
+
+// Name of the file pinning the Terraform version of a project.
+const ProjectLockFileName = ".tfs.lock"
+
+// ProjectLock records the Terraform version resolved for a project, so that
+// every machine uses the same binary, the same way .terraform.lock.hcl does
+// for providers.
+type ProjectLock struct {
+	// Resolved Terraform version.
+	Version string `json:"version"`
+
+	// Version requirement the version was resolved from.
+	Constraint string `json:"constraint,omitempty"`
+
+	// SHA256 sums of the release archives, by platform (<os>_<arch>).
+	Hashes map[string]string `json:"hashes"`
+
+	// Path of the lock file.
+	path string
+}
+
+// FindProjectLock looks for a lock file in the current directory and its
+// parents, with the same boundaries as FindVersionRequirement.
+// Returns nil, nil if there is no lock file.
+func FindProjectLock() (*ProjectLock, error) {
+	path, err := os.Getwd()
+	if err != nil {
+		slog.Error("Failed to get working directory", "error", err)
+		return nil, err
+	}
+
+	for _, dir := range searchDirectories(path) {
+		lock, err := ReadProjectLock(filepath.Join(dir, ProjectLockFileName))
+		if err != nil || lock != nil {
+			return lock, err
+		}
+	}
+
+	return nil, nil
+}
+
+// ReadProjectLock reads the given lock file. Returns nil, nil if it does not exist.
+func ReadProjectLock(path string) (*ProjectLock, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Failed to read lock file", "error", err, "fileName", path)
+		return nil, err
+	}
+
+	lock := &ProjectLock{path: path}
+	if err := json.Unmarshal(b, lock); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %w", path, err)
+	}
+	if _, err := version.NewVersion(lock.Version); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %w", path, err)
+	}
+
+	return lock, nil
+}
+
+// Path returns the path of the lock file.
+func (l *ProjectLock) Path() string {
+	return l.path
+}
+
+// TerraformVersion returns the locked Terraform version.
+func (l *ProjectLock) TerraformVersion() *version.Version {
+	// Validated when the lock file was read or created.
+	v, _ := version.NewVersion(l.Version)
+	return v
+}
+
+// Hash returns the locked archive checksum for the current platform.
+func (l *ProjectLock) Hash() (string, error) {
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+
+	hash, ok := l.Hashes[platform]
+	if !ok {
+		return "", fmt.Errorf("lock file %s has no checksum for platform %s; run 'tfs lock' to record it", l.path, platform)
+	}
+
+	return hash, nil
+}
+
+// Check makes sure the locked version still satisfies the project
+// version requirement, which may have changed since it was locked.
+func (l *ProjectLock) Check(req *Requirement) error {
+	if req == nil || req.Expression == "latest" || strings.HasPrefix(req.Expression, "latest:") {
+		// Pinning the latest release is the whole point of the lock.
+		return nil
+	}
+
+	constraint, err := version.NewConstraint(req.Expression)
+	if err != nil {
+		slog.Error("Failed to parse Terraform version constraint", "error", err, "constraint", req.Expression)
+		return err
+	}
+	if !constraint.Check(l.TerraformVersion()) {
+		return fmt.Errorf("locked version %s in %s does not satisfy constraint %q; run 'tfs lock --upgrade' to update it", l.Version, l.path, req.Expression)
+	}
+
+	return nil
+}
+
+// write saves the lock file.
+func (l *ProjectLock) write() error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(l.path, append(b, '\n'), 0644); err != nil {
+		slog.Error("Failed to write lock file", "error", err, "fileName", l.path)
+		return err
+	}
+
+	return nil
+}
+
+// LockProject resolves the version requirement of a project and records the
+// result in its lock file. An already locked version is kept as long as it
+// satisfies the requirement, unless upgrade is set, in which case the most
+// recent published release satisfying the requirement is selected.
+func (c *LocalCache) LockProject(req *Requirement, upgrade bool) (*ProjectLock, error) {
+	if req == nil {
+		return nil, errors.New("no Terraform version requirement found")
+	}
+
+	path := filepath.Join(req.Directory, ProjectLockFileName)
+	logger := slog.With("fileName", path)
+
+	previous, err := ReadProjectLock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var v *version.Version
+
+	if previous != nil && !upgrade && previous.Check(req) == nil {
+		v = previous.TerraformVersion()
+	} else {
+		strategy := viper.GetString("version_resolution")
+		if upgrade {
+			strategy = ResolveRemoteFirst
+		}
+		if v, err = c.resolveRequirement(req, strategy); err != nil {
+			return nil, err
+		}
+	}
+
+	source, err := c.Source()
+	if err != nil {
+		return nil, err
+	}
+	sums, err := source.Checksums(context.Background(), v)
+	if err != nil {
+		logger.Error("Failed to get Terraform release checksums", "error", err, "version", v.String())
+		return nil, err
+	}
+
+	lock := &ProjectLock{
+		Version:    v.String(),
+		Constraint: req.Expression,
+		Hashes:     platformHashes(v, sums),
+		path:       path,
+	}
+	if len(lock.Hashes) == 0 {
+		return nil, fmt.Errorf("no release archive checksums found for version %s", v)
+	}
+	if err := lock.write(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Locked Terraform version", "version", lock.Version, "previousVersion", previous.versionString())
+
+	return lock, nil
+}
+
+// versionString returns the locked version, or an empty string if there is no lock.
+func (l *ProjectLock) versionString() string {
+	if l == nil {
+		return ""
+	}
+	return l.Version
+}
+
+// platformHashes extracts the archive checksums of the given
+// version from a SHA256SUMS file, keyed by platform.
+func platformHashes(v *version.Version, sums Checksums) map[string]string {
+	prefix := terraformProductName + "_" + v.String() + "_"
+	hashes := make(map[string]string)
+
+	for fileName, sum := range sums {
+		platform, ok := strings.CutPrefix(fileName, prefix)
+		if !ok {
+			continue
+		}
+		if platform, ok = strings.CutSuffix(platform, ".zip"); ok {
+			hashes[platform] = sum
+		}
+	}
+
+	return hashes
+}
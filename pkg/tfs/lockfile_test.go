@@ -0,0 +1,170 @@
+package tfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLockProject(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.5.2"), []byte("dummy content"))
+
+	cache := NewLocalCache(cacheDir)
+	source := newFakeSource("1.5.2", "1.5.7", "1.6.3")
+	cache.SetSource(source)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ProjectLockFileName)
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+
+	lockProject := func(expr string, upgrade bool) *ProjectLock {
+		t.Helper()
+		if _, err := cache.LockProject(&Requirement{Expression: expr, Directory: dir}, upgrade); err != nil {
+			t.Fatalf("LockProject() failed: %v", err)
+		}
+		lock, err := ReadProjectLock(path)
+		if err != nil || lock == nil {
+			t.Fatalf("failed to read lock file: %v", err)
+		}
+		return lock
+	}
+
+	t.Run("initial lock", func(t *testing.T) {
+		lock := lockProject("~> 1.5", false)
+		if lock.Version != "1.5.2" {
+			t.Fatalf("expected cached version 1.5.2, got %s", lock.Version)
+		}
+		if hash, err := lock.Hash(); err != nil || hash != sha256Sum(source.binaries["1.5.2"]) {
+			t.Fatalf("unexpected hash for %s: %q (%v)", platform, hash, err)
+		}
+	})
+
+	t.Run("locked version is kept", func(t *testing.T) {
+		if lock := lockProject(">= 1.5", false); lock.Version != "1.5.2" {
+			t.Fatalf("expected locked version 1.5.2, got %s", lock.Version)
+		}
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		if lock := lockProject("~> 1.5.0", true); lock.Version != "1.5.7" {
+			t.Fatalf("expected upgraded version 1.5.7, got %s", lock.Version)
+		}
+	})
+
+	t.Run("requirement changed", func(t *testing.T) {
+		if lock := lockProject("~> 1.6.0", false); lock.Version != "1.6.3" {
+			t.Fatalf("expected version 1.6.3, got %s", lock.Version)
+		}
+	})
+
+	t.Run("no requirement", func(t *testing.T) {
+		if _, err := cache.LockProject(nil, false); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("no temporary file left", func(t *testing.T) {
+		files, err := os.ReadDir(dir)
+		if err != nil || len(files) != 1 || files[0].Name() != ProjectLockFileName {
+			t.Fatalf("expected only the lock file in the project directory, got %v (%v)", files, err)
+		}
+	})
+}
+
+func TestFindProjectLock(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.5.7"))
+
+	root := t.TempDir()
+	module := filepath.Join(root, "modules", "network")
+	if err := os.MkdirAll(module, 0755); err != nil {
+		t.Fatalf("Failed to create module: %v", err)
+	}
+	writeProjectFile(t, root, ".terraform-version", "1.5.7")
+
+	t.Chdir(module)
+	t.Setenv(SourceEnvironment, "")
+	viper.Set("version_search_boundary", root)
+
+	if lock, err := FindProjectLock(); err != nil || lock != nil {
+		t.Fatalf("expected no lock file, got %+v (%v)", lock, err)
+	}
+
+	req, err := FindVersionRequirement()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := cache.LockProject(req, false); err != nil {
+		t.Fatalf("LockProject() failed: %v", err)
+	}
+
+	lock, err := FindProjectLock()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lock == nil || lock.Path() != filepath.Join(root, ProjectLockFileName) || lock.Version != "1.5.7" {
+		t.Fatalf("expected 1.5.7 locked next to the version file, got %+v", lock)
+	}
+}
+
+func TestProjectLockCheck(t *testing.T) {
+	lock := &ProjectLock{Version: "1.5.7", path: ProjectLockFileName}
+
+	tests := []struct {
+		expr        string
+		shouldError bool
+	}{
+		{expr: "~> 1.5"},
+		{expr: "1.5.7"},
+		{expr: "latest"},
+		{expr: "latest:^1.6"},
+		{expr: "~> 1.6", shouldError: true},
+		{expr: "1.5.6", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			err := lock.Check(&Requirement{Expression: tt.expr})
+			if tt.shouldError && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestReleaseVerifyArchiveChecksum(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	source := newFakeSource("1.5.7")
+	cache.SetSource(source)
+
+	r := cache.NewRelease(mustVersion(t, "1.5.7"))
+	if err := r.Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+
+	if err := r.VerifyArchiveChecksum(sha256Sum(source.binaries["1.5.7"])); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.VerifyArchiveChecksum(sha256Sum([]byte("other"))); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
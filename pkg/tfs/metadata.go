@@ -0,0 +1,271 @@
+package tfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Name of the file holding metadata about cached releases.
+	metadataFileName = "index.json"
+
+	// Version of the metadata file format. Files with
+	// another version are rebuilt from the cache contents.
+	metadataFormatVersion = 1
+)
+
+// releaseMetadata holds what the file names in the cache do not tell
+// about a release. It is rebuilt from the binary and its checksum file
+// when missing, in which case the source and use history are unknown.
+type releaseMetadata struct {
+	InstalledAt time.Time  `json:"installedAt"`
+	LastUsed    *time.Time `json:"lastUsed,omitempty"`
+
+	// Location of the release archive, if known.
+	Source string `json:"source,omitempty"`
+
+	// Hex-encoded SHA256 sum of the binary.
+	Checksum string `json:"checksum,omitempty"`
+
+	Size   uint64 `json:"size"`
+	Pinned bool   `json:"pinned"`
+
+	// Project directories the release was selected for.
+	Projects []string `json:"projects,omitempty"`
+}
+
+// cacheMetadata is the contents of the metadata file.
+type cacheMetadata struct {
+	FormatVersion int                         `json:"formatVersion"`
+	Releases      map[string]*releaseMetadata `json:"releases"`
+}
+
+// metadataPath returns the path of the metadata file.
+func (c *LocalCache) metadataPath() string {
+	return filepath.Join(c.directory, metadataFileName)
+}
+
+// readMetadata reads the metadata file, and makes it consistent with the
+// given cached versions: entries of missing releases are dropped, and
+// entries of unknown releases are rebuilt from disk. The whole file is
+// rebuilt when it is missing or cannot be parsed, keeping the pins that
+// can still be read from it.
+func (c *LocalCache) readMetadata(versions []string) *cacheMetadata {
+	logger := slog.With("fileName", c.metadataPath())
+
+	m := &cacheMetadata{}
+
+	b, err := afero.ReadFile(AppFs, c.metadataPath())
+	if err == nil {
+		if err = json.Unmarshal(b, m); err == nil && m.FormatVersion != metadataFormatVersion {
+			err = fmt.Errorf("unsupported format version %d", m.FormatVersion)
+		}
+		if err != nil {
+			logger.Warn("Rebuilding invalid cache metadata", "error", err)
+			m = c.salvageMetadata(b)
+		}
+	}
+
+	m.FormatVersion = metadataFormatVersion
+	if m.Releases == nil {
+		m.Releases = make(map[string]*releaseMetadata)
+	}
+
+	for v := range m.Releases {
+		if !slices.Contains(versions, v) {
+			delete(m.Releases, v)
+		}
+	}
+	for _, v := range versions {
+		if _, ok := m.Releases[v]; !ok {
+			m.Releases[v] = c.rebuildReleaseMetadata(v)
+		}
+	}
+
+	return m
+}
+
+// salvageMetadata returns the pinned releases found in an invalid
+// metadata file, as pins cannot be rebuilt from disk.
+func (c *LocalCache) salvageMetadata(b []byte) *cacheMetadata {
+	m := &cacheMetadata{Releases: make(map[string]*releaseMetadata)}
+
+	var invalid struct {
+		Releases map[string]struct {
+			Pinned bool `json:"pinned"`
+		} `json:"releases"`
+	}
+	if err := json.Unmarshal(b, &invalid); err != nil {
+		slog.Warn("Pinned releases were lost; pin them again with 'tfs pin'", "error", err, "fileName", c.metadataPath())
+		return m
+	}
+
+	for v, rm := range invalid.Releases {
+		if rm.Pinned {
+			m.Releases[v] = c.rebuildReleaseMetadata(v)
+			m.Releases[v].Pinned = true
+		}
+	}
+
+	return m
+}
+
+// rebuildReleaseMetadata builds the metadata of a release from disk.
+func (c *LocalCache) rebuildReleaseMetadata(v string) *releaseMetadata {
+	fileName := viper.GetString("terraform_file_name_prefix") + v
+	path := filepath.Join(c.directory, fileName)
+
+	rm := &releaseMetadata{}
+
+	if fi, err := AppFs.Stat(path); err == nil {
+		rm.InstalledAt = fi.ModTime()
+		rm.Size = uint64(fi.Size())
+	}
+	if sums, err := readChecksumFile(path + checksumFileSuffix); err == nil {
+		rm.Checksum = sums[fileName]
+	}
+
+	return rm
+}
+
+// diskVersions returns the versions of the binaries found in the cache directory.
+func (c *LocalCache) diskVersions() []string {
+	files, _ := afero.Glob(AppFs, filepath.Join(c.directory, viper.GetString("terraform_file_name_prefix")+"*"))
+
+	versions := make([]string, 0, len(files))
+	for _, fileName := range files {
+		if strings.HasSuffix(fileName, checksumFileSuffix) {
+			continue
+		}
+		if v, err := versionFromFileName(filepath.Base(fileName)); err == nil {
+			versions = append(versions, v.String())
+		}
+	}
+
+	return versions
+}
+
+// updateMetadata applies the given changes to the metadata file. The file
+// is read again under the cache lock, so that changes made by other tfs
+// processes are not lost. Failures are logged as warnings, as most
+// metadata can be rebuilt from disk.
+func (c *LocalCache) updateMetadata(update func(m *cacheMetadata)) error {
+	logger := slog.With("fileName", c.metadataPath())
+
+	unlock, err := c.Lock()
+	if err != nil {
+		logger.Warn("Failed to update cache metadata", "error", err)
+		return err
+	}
+	defer unlock()
+
+	m := c.readMetadata(c.diskVersions())
+	update(m)
+
+	c.metadata = m
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(c.metadataPath(), b, 0644)
+	}
+	if err != nil {
+		logger.Warn("Failed to update cache metadata", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// metadata returns the metadata of the release.
+func (r *release) metadata() *releaseMetadata {
+	c := r.parentCache
+	if c.metadata == nil {
+		c.metadata = c.readMetadata(c.diskVersions())
+	}
+	if rm, ok := c.metadata.Releases[r.Version.String()]; ok {
+		return rm
+	}
+	return c.rebuildReleaseMetadata(r.Version.String())
+}
+
+// lastUse returns the time the release was last activated or run,
+// or the time it was installed if it was never used.
+func (r *release) lastUse() time.Time {
+	rm := r.metadata()
+	if rm.LastUsed != nil {
+		return *rm.LastUsed
+	}
+	return rm.InstalledAt
+}
+
+// recordInstall resets the metadata of a release that was just added
+// to the cache, keeping the settings made by the user.
+func (r *release) recordInstall(source string) {
+	r.parentCache.updateMetadata(func(m *cacheMetadata) {
+		v := r.Version.String()
+		rm := r.parentCache.rebuildReleaseMetadata(v)
+		rm.InstalledAt = time.Now()
+		rm.Source = source
+		if previous, ok := m.Releases[v]; ok {
+			rm.Pinned = previous.Pinned
+			rm.Projects = previous.Projects
+		}
+		m.Releases[v] = rm
+	})
+}
+
+// recordUse saves the time the release was last activated or run. This is
+// best-effort: the update is skipped when another process holds the cache
+// lock, so that running Terraform never waits for it.
+func (r *release) recordUse() {
+	unlock, err := r.parentCache.TryLock()
+	if err != nil {
+		slog.Debug("Skipping last use update", "error", err, "version", r.Version.String())
+		return
+	}
+	defer unlock()
+
+	r.parentCache.updateMetadata(func(m *cacheMetadata) {
+		if rm := m.Releases[r.Version.String()]; rm != nil {
+			now := time.Now()
+			rm.LastUsed = &now
+		}
+	})
+}
+
+// recordProject saves the project directory the release was selected for.
+// Like recordUse, this is skipped when another process holds the cache lock.
+func (r *release) recordProject(dir string) {
+	if slices.Contains(r.metadata().Projects, dir) {
+		return
+	}
+
+	unlock, err := r.parentCache.TryLock()
+	if err != nil {
+		slog.Debug("Skipping project update", "error", err, "version", r.Version.String())
+		return
+	}
+	defer unlock()
+
+	r.parentCache.updateMetadata(func(m *cacheMetadata) {
+		if rm := m.Releases[r.Version.String()]; rm != nil && !slices.Contains(rm.Projects, dir) {
+			rm.Projects = append(rm.Projects, dir)
+			slices.Sort(rm.Projects)
+		}
+	})
+}
+
+// forget drops the metadata of a release that left the cache.
+func (r *release) forget() {
+	r.parentCache.updateMetadata(func(m *cacheMetadata) {
+		delete(m.Releases, r.Version.String())
+	})
+}
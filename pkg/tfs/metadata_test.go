@@ -0,0 +1,174 @@
+package tfs
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// readTestMetadata reads the metadata file as written on disk.
+func readTestMetadata(t *testing.T, cacheDir string) *cacheMetadata {
+	t.Helper()
+
+	b, err := afero.ReadFile(AppFs, filepath.Join(cacheDir, metadataFileName))
+	if err != nil {
+		t.Fatalf("Failed to read metadata file: %v", err)
+	}
+	m := &cacheMetadata{}
+	if err := json.Unmarshal(b, m); err != nil {
+		t.Fatalf("Invalid metadata file: %v", err)
+	}
+	return m
+}
+
+func TestMetadataTracksReleaseLifecycle(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.10.0"))
+
+	r := cache.NewRelease(mustVersion(t, "1.10.0"))
+	if err := r.Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+
+	m := readTestMetadata(t, cacheDir)
+	rm, ok := m.Releases["1.10.0"]
+	if !ok {
+		t.Fatalf("Expected metadata for 1.10.0, got %+v", m.Releases)
+	}
+	if m.FormatVersion != metadataFormatVersion {
+		t.Errorf("Expected format version %d, got %d", metadataFormatVersion, m.FormatVersion)
+	}
+	if rm.Source != "fake://"+archiveName(r.Version) {
+		t.Errorf("Unexpected source %q", rm.Source)
+	}
+	if rm.Checksum != sha256Sum([]byte("terraform 1.10.0")) {
+		t.Errorf("Unexpected checksum %q", rm.Checksum)
+	}
+	if rm.Size != uint64(len("terraform 1.10.0")) || rm.InstalledAt.IsZero() {
+		t.Errorf("Unexpected size %d or install time %v", rm.Size, rm.InstalledAt)
+	}
+	if rm.LastUsed != nil {
+		t.Errorf("Expected no last use before activation, got %v", rm.LastUsed)
+	}
+
+	if err := r.Activate(); err != nil {
+		t.Fatalf("Activate() failed: %v", err)
+	}
+	if rm := readTestMetadata(t, cacheDir).Releases["1.10.0"]; rm.LastUsed == nil || rm.LastUsed.Before(rm.InstalledAt) {
+		t.Errorf("Expected last use to be recorded on activation, got %v", rm.LastUsed)
+	}
+
+	if err := r.Remove(); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if m := readTestMetadata(t, cacheDir); len(m.Releases) != 0 {
+		t.Errorf("Expected metadata to be dropped on removal, got %+v", m.Releases)
+	}
+}
+
+func TestMetadataRebuiltWhenCorrupt(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.9.0"), []byte("terraform 1.9.0"))
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.9.0"+checksumFileSuffix), []byte(Checksums{testFilePrefix + "1.9.0": sha256Sum([]byte("terraform 1.9.0"))}.String()))
+	writeTestFile(t, filepath.Join(cacheDir, metadataFileName), []byte("{not json"))
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	rm := cache.releases["1.9.0"].metadata()
+	if rm.Size != uint64(len("terraform 1.9.0")) || rm.Checksum != sha256Sum([]byte("terraform 1.9.0")) || rm.InstalledAt.IsZero() {
+		t.Errorf("Expected metadata to be rebuilt from disk, got %+v", rm)
+	}
+
+	// Rewritten on next update.
+	cache.releases["1.9.0"].recordUse()
+	if m := readTestMetadata(t, cacheDir); m.Releases["1.9.0"] == nil || m.Releases["1.9.0"].LastUsed == nil {
+		t.Errorf("Expected rebuilt metadata to be saved, got %+v", m.Releases)
+	}
+}
+
+func TestMetadataRebuildKeepsPins(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	for _, v := range []string{"1.8.0", "1.9.0"} {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte("terraform "+v))
+	}
+	writeTestFile(t, filepath.Join(cacheDir, metadataFileName), []byte(`{"formatVersion":99,"releases":{"1.8.0":{"pinned":true},"1.9.0":{"pinned":false}}}`))
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	if !cache.releases["1.8.0"].isPinned() || cache.releases["1.9.0"].isPinned() {
+		t.Errorf("Expected only 1.8.0 to stay pinned")
+	}
+	if rm := cache.releases["1.8.0"].metadata(); rm.Size != uint64(len("terraform 1.8.0")) {
+		t.Errorf("Expected metadata to be rebuilt from disk, got %+v", rm)
+	}
+}
+
+func TestMetadataReconciledWithDisk(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.9.0"), []byte("terraform 1.9.0"))
+	writeTestFile(t, filepath.Join(cacheDir, metadataFileName), []byte(`{
+  "formatVersion": 1,
+  "releases": {
+    "1.9.0": {"installedAt": "2024-01-02T03:04:05Z", "source": "https://example.com/1.9.0.zip", "size": 15, "pinned": false},
+    "1.8.0": {"installedAt": "2024-01-01T00:00:00Z", "size": 10, "pinned": false}
+  }
+}`))
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	list, err := cache.List()
+	if err != nil {
+		t.Fatalf("Cache.List() failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Source != "https://example.com/1.9.0.zip" || list[0].InstalledAt.Year() != 2024 {
+		t.Errorf("Expected recorded metadata for 1.9.0 only, got %+v", list)
+	}
+	if _, ok := cache.metadata.Releases["1.8.0"]; ok {
+		t.Errorf("Expected metadata of missing release to be dropped")
+	}
+}
+
+func TestMetadataRecordsProjects(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.10.0"))
+
+	projectDir := t.TempDir()
+	sel := &Selection{
+		Version:     mustVersion(t, "1.10.0"),
+		Requirement: &Requirement{Expression: "1.10.0", Directory: projectDir},
+	}
+	if _, err := cache.InstallSelection(sel); err != nil {
+		t.Fatalf("InstallSelection() failed: %v", err)
+	}
+	if _, err := cache.InstallSelection(sel); err != nil {
+		t.Fatalf("InstallSelection() failed: %v", err)
+	}
+
+	rm := readTestMetadata(t, cacheDir).Releases["1.10.0"]
+	if rm == nil || len(rm.Projects) != 1 || rm.Projects[0] != projectDir {
+		t.Errorf("Expected project %s to be recorded once, got %+v", projectDir, rm)
+	}
+}
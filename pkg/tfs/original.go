@@ -0,0 +1,226 @@
+package tfs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// Name of the cache subdirectory where the terraform binary found in the
+// user bin directory is backed up before being replaced.
+const originalDirName = "original"
+
+// ErrUnmanagedTerraform is returned when activating Terraform would
+// overwrite a terraform binary that was not installed by tfs.
+var ErrUnmanagedTerraform = errors.New("terraform binary not managed by tfs")
+
+// originalPath returns the path of the original terraform binary backup.
+func (c *LocalCache) originalPath() string {
+	return filepath.Join(c.directory, originalDirName, terraformProductName)
+}
+
+// protectExistingTerraform makes sure the file at the given path, if any,
+// can be replaced: it must be a link to the cache or to tfs itself.
+// Otherwise, it is backed up when the "force" setting is set, and also
+// added to the cache when the "adopt" setting is set.
+func (c *LocalCache) protectExistingTerraform(path string) error {
+	fi, _, err := AppFs.LstatIfPossible(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if c.isManaged(path, fi) {
+		return nil
+	}
+
+	if !viper.GetBool("force") && !viper.GetBool("adopt") {
+		return fmt.Errorf("%w: %s; run again with --force to back it up, or with --adopt to also add it to the cache", ErrUnmanagedTerraform, path)
+	}
+
+	if viper.GetBool("adopt") {
+		if err := c.adopt(path); err != nil {
+			return err
+		}
+	}
+
+	return c.backupOriginal(path)
+}
+
+// isManaged tells whether the file is a link created by tfs.
+func (c *LocalCache) isManaged(path string, fi os.FileInfo) bool {
+	if fi.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+
+	target, _, err := AppFs.ReadlinkIfPossible(path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	target = filepath.Clean(target)
+
+	if strings.HasPrefix(target, filepath.Clean(c.directory)+string(os.PathSeparator)) {
+		return true
+	}
+
+	// Shim.
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	return err == nil && target == executable
+}
+
+// adopt copies an existing Terraform binary into the cache.
+func (c *LocalCache) adopt(path string) error {
+	logger := slog.With("fileName", path)
+
+	v, err := terraformVersion(path)
+	if err != nil {
+		logger.Error("Failed to detect Terraform version", "error", err)
+		return err
+	}
+	logger = logger.With("version", v.String())
+
+	if _, ok := c.releases[v.String()]; ok {
+		logger.Info("Version is already cached")
+		return nil
+	}
+
+	b, err := afero.ReadFile(AppFs, path)
+	if err != nil {
+		logger.Error("Failed to read Terraform binary", "error", err)
+		return err
+	}
+
+	r := c.NewRelease(v)
+
+	// No archive checksum, as we do not know where the binary comes from.
+	sums := Checksums{r.fileName: sha256Sum(b)}
+	if err := writeFileAtomic(r.checksumPath(), []byte(sums.String()), 0644); err != nil {
+		logger.Error("Unable to record Terraform binary checksum", "error", err)
+		return err
+	}
+	if err := writeFileAtomic(r.path(), b, 0755); err != nil {
+		logger.Error("Unable to copy Terraform binary to cache", "error", err)
+		return err
+	}
+
+	r.recordInstall(path)
+
+	c.releases[v.String()] = r
+	if c.LastRelease == nil || v.GreaterThan(c.LastRelease.Version) {
+		c.LastRelease = r
+	}
+	logger.Info("Adopted existing Terraform binary")
+
+	return nil
+}
+
+// backupOriginal moves the file at the given path to the cache directory.
+func (c *LocalCache) backupOriginal(path string) error {
+	backup := c.originalPath()
+	logger := slog.With("fileName", path, "backup", backup)
+
+	if _, _, err := AppFs.LstatIfPossible(backup); err == nil {
+		return fmt.Errorf("a terraform binary is already backed up at %s; run 'tfs restore-original' or remove it first", backup)
+	}
+	if err := AppFs.MkdirAll(filepath.Dir(backup), os.ModePerm); err != nil {
+		logger.Error("Failed to create backup directory", "error", err)
+		return err
+	}
+	if err := moveFile(path, backup); err != nil {
+		logger.Error("Failed to back up existing terraform binary", "error", err)
+		return err
+	}
+
+	logger.Warn("Backed up existing terraform binary")
+
+	return nil
+}
+
+// RestoreOriginal puts back the terraform binary that was
+// backed up when Terraform was first activated with --force.
+func (c *LocalCache) RestoreOriginal() error {
+	var (
+		backup  = c.originalPath()
+		symlink = filepath.Join(viper.GetString("user_bin_directory"), terraformProductName)
+	)
+
+	logger := slog.With("fileName", symlink, "backup", backup)
+
+	unlock, err := c.Lock()
+	if err != nil {
+		logger.Error("Failed to lock cache directory", "error", err)
+		return err
+	}
+	defer unlock()
+
+	if _, _, err := AppFs.LstatIfPossible(backup); os.IsNotExist(err) {
+		return errors.New("no original terraform binary was backed up")
+	}
+
+	if fi, _, err := AppFs.LstatIfPossible(symlink); err == nil {
+		if !c.isManaged(symlink, fi) {
+			return fmt.Errorf("%w: %s; remove it before restoring the original one", ErrUnmanagedTerraform, symlink)
+		}
+		if err := AppFs.Remove(symlink); err != nil {
+			logger.Error("Failed to remove symlink", "error", err)
+			return err
+		}
+	}
+
+	if err := moveFile(backup, symlink); err != nil {
+		logger.Error("Failed to restore original terraform binary", "error", err)
+		return err
+	}
+
+	c.activeRelease = nil
+	logger.Info("Restored original terraform binary")
+
+	return nil
+}
+
+// moveFile renames a file or a link, copying it when the destination
+// is on another filesystem.
+func moveFile(src, dst string) error {
+	err := AppFs.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	fi, _, lerr := AppFs.LstatIfPossible(src)
+	if lerr != nil {
+		return err
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, _, err := AppFs.ReadlinkIfPossible(src)
+		if err != nil {
+			return err
+		}
+		if err := AppFs.SymlinkIfPossible(target, dst); err != nil {
+			return err
+		}
+	} else {
+		b, err := afero.ReadFile(AppFs, src)
+		if err != nil {
+			return err
+		}
+		if err := afero.WriteFile(AppFs, dst, b, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	return AppFs.Remove(src)
+}
@@ -0,0 +1,95 @@
+package tfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestReleaseActivateProtectsExistingBinary(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	symlink := filepath.Join(viper.GetString("user_bin_directory"), "terraform")
+	original := []byte("#!/bin/sh\necho '{\"terraform_version\": \"1.2.3\"}'\n")
+	if err := os.MkdirAll(filepath.Dir(symlink), 0755); err != nil {
+		t.Fatalf("Failed to create bin dir: %v", err)
+	}
+	if err := os.WriteFile(symlink, original, 0755); err != nil {
+		t.Fatalf("Failed to write existing binary: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.10.0"), []byte("dummy content"))
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+	release := cache.NewRelease(mustVersion(t, "1.10.0"))
+
+	t.Run("refused by default", func(t *testing.T) {
+		if err := release.Activate(); !errors.Is(err, ErrUnmanagedTerraform) {
+			t.Fatalf("expected ErrUnmanagedTerraform, got %v", err)
+		}
+		if b, err := os.ReadFile(symlink); err != nil || string(b) != string(original) {
+			t.Fatalf("existing binary was modified")
+		}
+	})
+
+	t.Run("backed up and adopted", func(t *testing.T) {
+		viper.Set("adopt", true)
+		defer viper.Set("adopt", false)
+
+		if err := release.Activate(); err != nil {
+			t.Fatalf("Activate() failed: %v", err)
+		}
+		if target, err := os.Readlink(symlink); err != nil || target != release.path() {
+			t.Fatalf("expected symlink to the cached binary, got %q (%v)", target, err)
+		}
+		if b, err := os.ReadFile(cache.originalPath()); err != nil || string(b) != string(original) {
+			t.Fatalf("existing binary was not backed up: %v", err)
+		}
+		if _, ok := cache.releases["1.2.3"]; !ok {
+			t.Fatalf("existing binary was not adopted")
+		}
+		if b, err := readTestFile(filepath.Join(cacheDir, testFilePrefix+"1.2.3")); err != nil || string(b) != string(original) {
+			t.Fatalf("adopted binary not found in cache: %v", err)
+		}
+	})
+
+	t.Run("restored", func(t *testing.T) {
+		if err := cache.RestoreOriginal(); err != nil {
+			t.Fatalf("RestoreOriginal() failed: %v", err)
+		}
+		fi, err := os.Lstat(symlink)
+		if err != nil || fi.Mode()&os.ModeSymlink != 0 {
+			t.Fatalf("expected the original regular file, got %v (%v)", fi, err)
+		}
+		if err := cache.RestoreOriginal(); err == nil {
+			t.Fatal("expected error when nothing is backed up, got nil")
+		}
+	})
+
+	t.Run("symlink outside the cache", func(t *testing.T) {
+		os.Remove(symlink)
+		if err := os.Symlink("/usr/local/bin/terraform", symlink); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		if err := release.Activate(); !errors.Is(err, ErrUnmanagedTerraform) {
+			t.Fatalf("expected ErrUnmanagedTerraform, got %v", err)
+		}
+
+		viper.Set("force", true)
+		defer viper.Set("force", false)
+
+		if err := release.Activate(); err != nil {
+			t.Fatalf("Activate() failed: %v", err)
+		}
+		if target, err := os.Readlink(cache.originalPath()); err != nil || target != "/usr/local/bin/terraform" {
+			t.Fatalf("expected the symlink to be backed up, got %q (%v)", target, err)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package tfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Output formats.
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputTable = "table"
+)
+
+// Supported output formats.
+var OutputFormats = []string{OutputText, OutputJSON, OutputYAML, OutputTable}
+
+// Report is the data printed by a command.
+type Report interface {
+	// WriteText writes the report in a human readable form.
+	WriteText(w io.Writer) error
+}
+
+// tableReport is a report that can be printed as a table.
+type tableReport interface {
+	// tableRows returns the table header followed by the table rows.
+	tableRows() [][]string
+}
+
+// CheckOutputFormat makes sure the output format is supported.
+func CheckOutputFormat(format string) error {
+	for _, f := range OutputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format %q, expected one of %s", format, strings.Join(OutputFormats, ", "))
+}
+
+// WriteOutput writes the report in the given format. Reports that
+// cannot be printed as a table are printed as text instead.
+func WriteOutput(w io.Writer, format string, report Report) error {
+	if err := CheckOutputFormat(format); err != nil {
+		return err
+	}
+
+	switch format {
+	case OutputJSON:
+		return writeJSON(w, report)
+	case OutputYAML:
+		return writeYAML(w, report)
+	case OutputTable:
+		if t, ok := report.(tableReport); ok {
+			return writeTable(w, t.tableRows())
+		}
+	}
+
+	return report.WriteText(w)
+}
+
+// writeJSON writes the value as an indented JSON document.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// writeYAML writes the value as a YAML document. The value is encoded
+// to JSON first, so that field names and order match the JSON output.
+func writeYAML(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// blockStyle drops the flow style inherited from the JSON document,
+// except for empty collections.
+func blockStyle(node *yaml.Node) {
+	if len(node.Content) > 0 || node.Kind == yaml.ScalarNode {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// writeTable writes the rows as aligned columns.
+func writeTable(w io.Writer, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,148 @@
+package tfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/spf13/afero"
+)
+
+func TestCacheList(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	for _, v := range []string{"1.10.0", "1.9.0"} {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte("terraform "+v))
+	}
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+	if err := cache.releases["1.9.0"].Activate(); err != nil {
+		t.Fatalf("Release.Activate() failed: %v", err)
+	}
+
+	list, err := cache.List()
+	if err != nil {
+		t.Fatalf("Cache.List() failed: %v", err)
+	}
+
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 releases, got %d", len(list))
+	}
+	if list[0].Version != "1.9.0" || !list[0].Active {
+		t.Errorf("Expected active 1.9.0 first, got %+v", list[0])
+	}
+	if list[1].Version != "1.10.0" || list[1].Active {
+		t.Errorf("Expected inactive 1.10.0 last, got %+v", list[1])
+	}
+	if list[1].Size != uint64(len("terraform 1.10.0")) {
+		t.Errorf("Expected size %d, got %d", len("terraform 1.10.0"), list[1].Size)
+	}
+	if fi, _ := AppFs.Stat(filepath.Join(cacheDir, testFilePrefix+"1.10.0")); !list[1].InstalledAt.Equal(fi.ModTime()) {
+		t.Errorf("Expected install time %v, got %v", fi.ModTime(), list[1].InstalledAt)
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	color.NoColor = true
+
+	list := ReleaseList{
+		{Version: "1.9.0", Size: 1000, Active: true, Source: "releases.hashicorp.com"},
+		{Version: "1.10.0", Size: 2000},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{OutputText, "1.9.0 (active)\n1.10.0\n"},
+		{OutputTable, "VERSION  SIZE    INSTALLED  LAST USED  ACTIVE  PINNED  SOURCE\n1.9.0    1.0 kB  -          -          yes     no      releases.hashicorp.com\n1.10.0   2.0 kB  -          -          no      no      -\n"},
+	}
+
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := WriteOutput(&b, tt.format, list); err != nil {
+			t.Fatalf("WriteOutput(%s) failed: %v", tt.format, err)
+		}
+		if b.String() != tt.expected {
+			t.Errorf("Unexpected %s output:\n%s\nexpected:\n%s", tt.format, b.String(), tt.expected)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := WriteOutput(&b, OutputJSON, list); err != nil {
+		t.Fatalf("WriteOutput(json) failed: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(decoded) != 2 || decoded[0]["version"] != "1.9.0" || decoded[0]["active"] != true {
+		t.Errorf("Unexpected JSON output: %s", b.String())
+	}
+
+	b.Reset()
+	if err := WriteOutput(&b, OutputYAML, list); err != nil {
+		t.Fatalf("WriteOutput(yaml) failed: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "- version: 1.9.0\n  size: 1000\n") {
+		t.Errorf("Unexpected YAML output:\n%s", b.String())
+	}
+
+	if err := WriteOutput(&b, "xml", list); err == nil {
+		t.Errorf("Expected unsupported output format to fail")
+	}
+}
+
+func TestWriteOutput_YAMLQuoting(t *testing.T) {
+	var b bytes.Buffer
+
+	// Versions that look like numbers must stay strings.
+	report := &PruneReport{Removed: []ReleaseInfo{{Version: "1.10"}}}
+	if err := WriteOutput(&b, OutputYAML, report); err != nil {
+		t.Fatalf("WriteOutput(yaml) failed: %v", err)
+	}
+	if !strings.Contains(b.String(), `version: "1.10"`) {
+		t.Errorf("Expected version to be quoted:\n%s", b.String())
+	}
+
+	// Empty lists.
+	b.Reset()
+	if err := WriteOutput(&b, OutputYAML, &PruneReport{Removed: []ReleaseInfo{}}); err != nil {
+		t.Fatalf("WriteOutput(yaml) failed: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "removed: []\n") {
+		t.Errorf("Expected empty list in flow style:\n%s", b.String())
+	}
+}
+
+func TestCachePrune_RemovesNothingFromEmptyCache(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	if err := AppFs.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatalf("Failed to create cache dir: %v", err)
+	}
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	report, err := cache.Prune(false)
+	if err != nil {
+		t.Fatalf("Cache.Prune() failed: %v", err)
+	}
+	if report.Removed == nil || len(report.Removed) != 0 {
+		t.Errorf("Expected an empty list of removed releases, got %#v", report.Removed)
+	}
+	if exists, _ := afero.DirExists(AppFs, cacheDir); !exists {
+		t.Errorf("Expected cache directory to remain")
+	}
+}
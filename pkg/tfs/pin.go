@@ -0,0 +1,76 @@
+package tfs
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/viper"
+)
+
+// Pin protects a cached release from "prune", "prune-until" and the
+// automatic cleanup. Pins are kept in the cache metadata.
+func (c *LocalCache) Pin(v *version.Version) error {
+	return c.setPinned(v, true)
+}
+
+// Unpin removes the protection set by Pin.
+func (c *LocalCache) Unpin(v *version.Version) error {
+	if err := c.setPinned(v, false); err != nil {
+		return err
+	}
+	if pinnedByConfig(v) {
+		slog.Warn("Version is still pinned by the 'pinned_versions' setting", "version", v.String())
+	}
+	return nil
+}
+
+// setPinned updates the pinned status of a cached release.
+func (c *LocalCache) setPinned(v *version.Version, pinned bool) error {
+	logger := slog.With("version", v.String())
+
+	if _, ok := c.releases[v.String()]; !ok {
+		return fmt.Errorf("Terraform %s is not in cache; run 'tfs %s' to install it", v, v)
+	}
+
+	if err := c.updateMetadata(func(m *cacheMetadata) {
+		rm := m.Releases[v.String()]
+		if rm == nil {
+			rm = c.rebuildReleaseMetadata(v.String())
+			m.Releases[v.String()] = rm
+		}
+		rm.Pinned = pinned
+	}); err != nil {
+		logger.Error("Failed to save pinned status", "error", err)
+		return err
+	}
+
+	if pinned {
+		logger.Info("Pinned release")
+	} else {
+		logger.Info("Unpinned release")
+	}
+
+	return nil
+}
+
+// isPinned tells whether the release is pinned, either
+// with "tfs pin" or with the "pinned_versions" setting.
+func (r *release) isPinned() bool {
+	return r.metadata().Pinned || pinnedByConfig(r.Version)
+}
+
+// pinnedByConfig tells whether the version is listed in the "pinned_versions" setting.
+func pinnedByConfig(v *version.Version) bool {
+	for _, raw := range viper.GetStringSlice("pinned_versions") {
+		pinned, err := version.NewVersion(raw)
+		if err != nil {
+			slog.Warn("Ignoring invalid pinned version", "error", err, "version", raw)
+			continue
+		}
+		if pinned.Equal(v) {
+			return true
+		}
+	}
+	return false
+}
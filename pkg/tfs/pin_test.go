@@ -0,0 +1,146 @@
+package tfs
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// newPinTestCache returns a loaded cache holding the given versions.
+func newPinTestCache(t *testing.T, cacheDir string, versions ...string) *LocalCache {
+	t.Helper()
+
+	for _, v := range versions {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte("terraform "+v))
+	}
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+	return cache
+}
+
+func TestPinPersisted(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := newPinTestCache(t, cacheDir, "1.5.7", "1.6.0")
+
+	if err := cache.Pin(mustVersion(t, "1.5.7")); err != nil {
+		t.Fatalf("Pin() failed: %v", err)
+	}
+	if err := cache.Pin(mustVersion(t, "1.9.0")); err == nil {
+		t.Errorf("Expected pinning a version that is not cached to fail")
+	}
+
+	// Read back from disk.
+	cache = newPinTestCache(t, cacheDir)
+	list, err := cache.List()
+	if err != nil {
+		t.Fatalf("Cache.List() failed: %v", err)
+	}
+	if !list[0].Pinned || list[1].Pinned {
+		t.Errorf("Expected only 1.5.7 to be pinned, got %+v", list)
+	}
+
+	if err := cache.Unpin(mustVersion(t, "1.5.7")); err != nil {
+		t.Fatalf("Unpin() failed: %v", err)
+	}
+	if cache.releases["1.5.7"].isPinned() {
+		t.Errorf("Expected 1.5.7 to be unpinned")
+	}
+}
+
+func TestPinnedVersionsSetting(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("pinned_versions", []string{"1.5.7", "not-a-version"})
+
+	cache := newPinTestCache(t, cacheDir, "1.5.7", "1.6.0")
+
+	if !cache.releases["1.5.7"].isPinned() {
+		t.Errorf("Expected 1.5.7 to be pinned by configuration")
+	}
+	if cache.releases["1.6.0"].isPinned() {
+		t.Errorf("Expected 1.6.0 not to be pinned")
+	}
+}
+
+func TestPruneKeepsPinnedReleases(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := newPinTestCache(t, cacheDir, "1.5.7", "1.6.0", "1.7.0")
+	if err := cache.Pin(mustVersion(t, "1.5.7")); err != nil {
+		t.Fatalf("Pin() failed: %v", err)
+	}
+
+	report, err := cache.PruneUntil(mustVersion(t, "1.7.0"), false)
+	if err != nil {
+		t.Fatalf("Cache.PruneUntil() failed: %v", err)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Version != "1.6.0" {
+		t.Errorf("Expected only 1.6.0 to be removed, got %+v", report.Removed)
+	}
+
+	report, err = cache.Prune(false)
+	if err != nil {
+		t.Fatalf("Cache.Prune() failed: %v", err)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Version != "1.7.0" {
+		t.Errorf("Expected only 1.7.0 to be removed, got %+v", report.Removed)
+	}
+
+	report, err = cache.Prune(true)
+	if err != nil {
+		t.Fatalf("Cache.Prune() failed: %v", err)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Version != "1.5.7" {
+		t.Errorf("Expected pinned 1.5.7 to be removed, got %+v", report.Removed)
+	}
+}
+
+func TestAutoCleanKeepsPinnedReleases(t *testing.T) {
+	tests := map[string]func(){
+		"history": func() {
+			viper.Set("cache_history", 1)
+		},
+		"lru": func() {
+			viper.Set("cache_policy", CachePolicyLRU)
+			viper.Set("cache_history", 1)
+		},
+		"minor and patch": func() {
+			viper.Set("cache_minor_version_nb", 1)
+			viper.Set("cache_patch_version_nb", 1)
+		},
+		"quota": func() {
+			viper.Set("cache_auto_clean", false)
+			viper.Set("cache_max_size", "16B")
+		},
+	}
+
+	for name, configure := range tests {
+		t.Run(name, func(t *testing.T) {
+			cacheDir, cleanup := initTestFS(t)
+			defer cleanup()
+
+			viper.Set("cache_auto_clean", true)
+			configure()
+
+			cache := newPinTestCache(t, cacheDir, "0.13.7", "1.6.0")
+			if err := cache.Pin(mustVersion(t, "0.13.7")); err != nil {
+				t.Fatalf("Pin() failed: %v", err)
+			}
+
+			cache.AutoClean()
+
+			if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"0.13.7")); !exists {
+				t.Errorf("Expected pinned 0.13.7 to remain")
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package tfs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/viper"
+)
+
+// ErrCacheQuotaExceeded is returned in strict mode when a Terraform
+// binary does not fit in the cache quota on its own.
+var ErrCacheQuotaExceeded = errors.New("cache quota exceeded")
+
+// cacheMaxSize returns the cache quota in bytes, or zero if there is none.
+func cacheMaxSize() (uint64, error) {
+	s := viper.GetString("cache_max_size")
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+
+	size, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache_max_size %q: %w", s, err)
+	}
+
+	return size, nil
+}
+
+// evictionOrder returns the cached releases in the order the retention
+// policy removes them: least recently used first with the "lru" policy,
+// lowest version first otherwise.
+func (c *LocalCache) evictionOrder() []*release {
+	releases := c.sortedReleases()
+
+	if viper.GetString("cache_policy") == CachePolicyLRU {
+		sort.SliceStable(releases, func(i, j int) bool {
+			return releases[i].lastUse().Before(releases[j].lastUse())
+		})
+	}
+
+	return releases
+}
+
+// makeRoom removes releases following the retention policy, until the
+// cached binaries and the given extra size fit in the quota.
+func (c *LocalCache) makeRoom(keep *release, extra uint64) error {
+	releases, err := c.quotaEvictions(keep, extra, nil)
+	if err != nil || len(releases) == 0 {
+		return err
+	}
+
+	report, err := c.removeAll(releases)
+	report.log(c.directory)
+
+	return err
+}
+
+// quotaEvictions returns the releases to remove, following the retention
+// policy, so that the cached binaries and the given extra size fit in the
+// quota, assuming the planned releases are removed. The given release, the
+// active one, the one just installed and pinned ones are never returned.
+func (c *LocalCache) quotaEvictions(keep *release, extra uint64, planned []*release) ([]*release, error) {
+	quota, err := cacheMaxSize()
+	if err != nil || quota == 0 {
+		return nil, err
+	}
+
+	used := extra
+	for _, r := range c.releases {
+		if r.SameAs(keep) || slices.Contains(planned, r) {
+			continue
+		}
+		size, err := r.Size()
+		if err != nil {
+			return nil, err
+		}
+		used += size
+	}
+
+	var evictions []*release
+
+	for _, r := range c.evictionOrder() {
+		if used <= quota {
+			break
+		}
+		if r.SameAs(keep) || r.SameAs(c.activeRelease) || r.SameAs(c.currentRelease) || r.isPinned() || slices.Contains(planned, r) {
+			continue
+		}
+		size, err := r.Size()
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Removing Terraform binary to fit in cache quota", "version", r.Version.String(), "cacheMaxSize", formatSize(quota))
+		evictions = append(evictions, r)
+		used -= size
+	}
+
+	if used > quota {
+		slog.Warn("Cache does not fit in quota", "cacheSize", formatSize(used), "cacheMaxSize", formatSize(quota))
+	}
+
+	return evictions, nil
+}
+
+// checkQuota makes sure the freshly downloaded binary fits in the cache
+// quota on its own. Otherwise, a warning is logged, or the binary is removed
+// and an error returned when the "strict" setting is set.
+func (r *release) checkQuota() error {
+	quota, err := cacheMaxSize()
+	if err != nil || quota == 0 {
+		return err
+	}
+
+	size, err := r.Size()
+	if err != nil {
+		return err
+	}
+	if size <= quota {
+		return nil
+	}
+
+	err = fmt.Errorf("%w: Terraform %s alone takes %s, more than cache_max_size (%s)", ErrCacheQuotaExceeded, r.Version, formatSize(size), formatSize(quota))
+	if viper.GetBool("strict") {
+		r.Remove()
+		return err
+	}
+
+	slog.Warn("Terraform binary does not fit in cache quota", "error", err)
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package tfs
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+func TestCacheMaxSize(t *testing.T) {
+	_, cleanup := initTestFS(t)
+	defer cleanup()
+
+	tests := map[string]uint64{
+		"":      0,
+		"1GiB":  1 << 30,
+		"500MB": 500 * 1000 * 1000,
+		"1024":  1024,
+	}
+	for value, expected := range tests {
+		viper.Set("cache_max_size", value)
+		size, err := cacheMaxSize()
+		if err != nil {
+			t.Errorf("cacheMaxSize(%q) failed: %v", value, err)
+		}
+		if size != expected {
+			t.Errorf("cacheMaxSize(%q): expected %d, got %d", value, expected, size)
+		}
+	}
+
+	viper.Set("cache_max_size", "lots")
+	if _, err := cacheMaxSize(); err == nil {
+		t.Errorf("Expected invalid size to fail")
+	}
+}
+
+func TestReleaseInstallMakesRoom(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_max_size", "45B")
+
+	for _, v := range []string{"1.5.0", "1.6.0"} {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte(strings.Repeat("x", 20)))
+	}
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.10.0"))
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	// 40 bytes are cached, and the new binary is expected to take 20 bytes.
+	if err := cache.NewRelease(mustVersion(t, "1.10.0")).Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+
+	if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.5.0")); exists {
+		t.Errorf("Expected lowest version to be removed")
+	}
+	for _, v := range []string{"1.6.0", "1.10.0"} {
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v)); !exists {
+			t.Errorf("Expected version %s to remain", v)
+		}
+	}
+}
+
+func TestReleaseInstallFailureKeepsCache(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_max_size", "45B")
+
+	for _, v := range []string{"1.5.0", "1.6.0"} {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte(strings.Repeat("x", 20)))
+	}
+
+	cache := NewLocalCache(cacheDir)
+	// The source does not publish the requested version.
+	cache.SetSource(newFakeSource("1.9.0"))
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	if err := cache.NewRelease(mustVersion(t, "1.10.0")).Install(); err == nil {
+		t.Fatalf("Expected Install() to fail")
+	}
+
+	for _, v := range []string{"1.5.0", "1.6.0"} {
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v)); !exists {
+			t.Errorf("Expected version %s to remain after a failed installation", v)
+		}
+	}
+}
+
+func TestReleaseInstallLargerThanQuota(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	viper.Set("cache_max_size", "10B")
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.10.0"))
+	r := cache.NewRelease(mustVersion(t, "1.10.0"))
+
+	// Only a warning by default.
+	if err := r.Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+	if err := r.Remove(); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+
+	viper.Set("strict", true)
+
+	err := r.Install()
+	if !errors.Is(err, ErrCacheQuotaExceeded) {
+		t.Fatalf("Expected ErrCacheQuotaExceeded, got %v", err)
+	}
+	if exists, _ := afero.Exists(AppFs, r.path()); exists {
+		t.Errorf("Expected binary to be removed in strict mode")
+	}
+}
+
+func TestCacheAutoClean_Quota(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	// Quota is honoured even without automatic cleanup.
+	viper.Set("cache_auto_clean", false)
+	viper.Set("cache_max_size", "45B")
+
+	for _, v := range []string{"1.5.0", "1.6.0", "1.7.0"} {
+		writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+v), []byte(strings.Repeat("x", 20)))
+	}
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+	if err := cache.releases["1.5.0"].Activate(); err != nil {
+		t.Fatalf("Release.Activate() failed: %v", err)
+	}
+
+	cache.AutoClean()
+
+	// The lowest version is active.
+	if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.6.0")); exists {
+		t.Errorf("Expected 1.6.0 to be removed")
+	}
+	for _, v := range []string{"1.5.0", "1.7.0"} {
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v)); !exists {
+			t.Errorf("Expected version %s to remain", v)
+		}
+	}
+}
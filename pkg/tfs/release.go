@@ -2,15 +2,18 @@ package tfs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/hashicorp/go-version"
-	install "github.com/hashicorp/hc-install"
-	"github.com/hashicorp/hc-install/product"
-	"github.com/hashicorp/hc-install/releases"
-	"github.com/hashicorp/hc-install/src"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
 
@@ -33,38 +36,10 @@ func (r *release) Install() error {
 	// installed, download it otherwise.
 	targetPath := filepath.Join(r.parentCache.directory, r.fileName)
 
-	// Ensure parent cache directory exists.
-	if err := AppFs.MkdirAll(filepath.Dir(targetPath), os.ModePerm); err != nil {
-		logger.Error("Failed to create cache directory", "error", err)
-		return err
-	}
-
+	// Only take the lock when a download is needed, so that
+	// cached versions never wait for another tfs process.
 	if _, err := AppFs.Stat(targetPath); os.IsNotExist(err) {
-		ctx := context.Background()
-		i := install.NewInstaller()
-		defer i.Remove(ctx)
-
-		logger.Info("Downloading Terraform")
-
-		srcPath, err := i.Install(ctx, []src.Installable{
-			&releases.ExactVersion{
-				Product: product.Terraform,
-				Version: r.Version,
-			},
-		})
-		if err != nil {
-			logger.Error("Download failed", "error", err)
-			return err
-		}
-		// Move downloaded file.
-		b, err := os.ReadFile(srcPath)
-		if err != nil {
-			logger.Error("Unable to read downloaded file", "error", err, "srcPath", srcPath)
-			return err
-		}
-		err = os.WriteFile(targetPath, b, os.ModePerm)
-		if err != nil {
-			logger.Error("Unable to move downloaded file to cache", "error", err, "targetPath", targetPath)
+		if err := r.installLocked(logger); err != nil {
 			return err
 		}
 	}
@@ -77,9 +52,139 @@ func (r *release) Install() error {
 	return nil
 }
 
+// installLocked downloads the Terraform binary under the cache lock,
+// unless another process installed it while we were waiting.
+func (r *release) installLocked(logger *slog.Logger) error {
+	unlock, err := r.parentCache.Lock()
+	if err != nil {
+		logger.Error("Failed to lock cache directory", "error", err)
+		return err
+	}
+	defer unlock()
+
+	targetPath := filepath.Join(r.parentCache.directory, r.fileName)
+
+	// Ensure parent cache directory exists.
+	if err := AppFs.MkdirAll(filepath.Dir(targetPath), os.ModePerm); err != nil {
+		logger.Error("Failed to create cache directory", "error", err)
+		return err
+	}
+
+	if _, err := AppFs.Stat(targetPath); !os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := r.download(logger); err != nil {
+		return err
+	}
+	if err := r.checkQuota(); err != nil {
+		logger.Error("Refusing to install Terraform binary", "error", err)
+		return err
+	}
+	// Only make room once the new binary is there, so that
+	// a failed installation never costs any cached release.
+	size, _ := r.Size()
+	if err := r.parentCache.makeRoom(r, size); err != nil {
+		logger.Error("Failed to enforce cache quota", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// download fetches the Terraform binary from the release source. The binary
+// is written to a temporary file that is only renamed into place once
+// complete, so that an interruption never leaves a truncated release behind.
+func (r *release) download(logger *slog.Logger) error {
+	targetPath := filepath.Join(r.parentCache.directory, r.fileName)
+
+	source, err := r.parentCache.Source()
+	if err != nil {
+		return err
+	}
+
+	// Abort the download on Ctrl-C, and clean up the partial file.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tmp, err := afero.TempFile(AppFs, r.parentCache.directory, "."+r.fileName+".*"+tempFileSuffix)
+	if err != nil {
+		logger.Error("Unable to create temporary file in cache", "error", err)
+		return err
+	}
+	defer func() {
+		// No-op once the file has been renamed.
+		tmp.Close()
+		AppFs.Remove(tmp.Name())
+	}()
+
+	logger.Info("Downloading Terraform", "source", source.Name())
+
+	h := sha256.New()
+	artifact, err := source.Fetch(ctx, r.Version, io.MultiWriter(tmp, h))
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		logger.Error("Download failed", "error", err)
+		return err
+	}
+	if !artifact.Verified {
+		logger.Warn("Terraform archive was not verified against signed checksums", "url", artifact.URL)
+	}
+
+	// Make sure the data reached the disk before renaming the file.
+	if err := tmp.Sync(); err != nil {
+		logger.Error("Unable to write downloaded file to cache", "error", err)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		logger.Error("Unable to write downloaded file to cache", "error", err)
+		return err
+	}
+	if err := AppFs.Chmod(tmp.Name(), 0755); err != nil {
+		logger.Error("Unable to make downloaded file executable", "error", err)
+		return err
+	}
+
+	// Record the archive checksum and the binary checksum, so that
+	// we can later make sure the binary has not been tampered with.
+	sums := Checksums{
+		artifact.FileName: artifact.SHA256,
+		r.fileName:        hex.EncodeToString(h.Sum(nil)),
+	}
+	if err := writeFileAtomic(r.checksumPath(), []byte(sums.String()), 0644); err != nil {
+		logger.Error("Unable to record Terraform binary checksum", "error", err)
+		return err
+	}
+
+	// Move downloaded file.
+	if err := AppFs.Rename(tmp.Name(), targetPath); err != nil {
+		logger.Error("Unable to move downloaded file to cache", "error", err, "targetPath", targetPath)
+		return err
+	}
+
+	r.recordInstall(artifact.URL)
+
+	return nil
+}
+
 // Activate creates the symbolic link in the user path that
 // points to the desired Terraform binary.
 func (r *release) Activate() error {
+	unlock, err := r.parentCache.Lock()
+	if err != nil {
+		slog.Error("Failed to lock cache directory", "error", err, "version", r.Version.String())
+		return err
+	}
+	defer unlock()
+
+	// In shim mode, the symlink points to tfs itself, which
+	// selects the binary required by the current directory.
+	if viper.GetString("activation_mode") == ActivationModeShim {
+		return r.parentCache.InstallShim()
+	}
+
 	var (
 		userBinDir = viper.GetString("user_bin_directory")
 		symlink    = filepath.Join(userBinDir, "terraform")
@@ -106,12 +211,25 @@ func (r *release) Activate() error {
 		"symlink", symlink,
 	)
 
+	// Never activate a binary that does not match its recorded checksum.
+	if err := r.VerifyChecksum(); err != nil {
+		activateLogger.Error("Refusing to activate Terraform binary", "error", err)
+		return err
+	}
+
 	// Check if the desired version is already active.
 	if r.SameAs(r.parentCache.activeRelease) {
 		activateLogger.Info("Version is already active")
+		r.recordUse()
 		return nil
 	}
 
+	// Never overwrite a terraform binary that tfs did not install.
+	if err := r.parentCache.protectExistingTerraform(symlink); err != nil {
+		activateLogger.Error("Refusing to replace existing terraform binary", "error", err)
+		return err
+	}
+
 	// Remove the link if it exists.
 	if _, b, err := AppFs.LstatIfPossible(symlink); !b {
 		activateLogger.Warn("The operating system does not seem to support `os.Lstat`", "error", err)
@@ -126,6 +244,7 @@ func (r *release) Activate() error {
 	}
 
 	r.parentCache.activeRelease = r
+	r.recordUse()
 	activateLogger.Info("New active version")
 
 	return nil
@@ -144,6 +263,13 @@ func (r *release) Remove() error {
 		"fileName", target,
 	)
 
+	unlock, err := r.parentCache.Lock()
+	if err != nil {
+		logger.Error("Failed to lock cache directory", "error", err)
+		return err
+	}
+	defer unlock()
+
 	// Check if we should also remove the symbolic link.
 	if path, ok, _ := AppFs.EvalSymlinksIfPossible(symlink); ok && path == target {
 		AppFs.Remove(symlink)
@@ -153,13 +279,86 @@ func (r *release) Remove() error {
 		logger.Error("Failed to remove Terraform binary", "error", err)
 		return err
 	}
+	if err := AppFs.Remove(r.checksumPath()); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove Terraform binary checksum", "error", err)
+	}
+	// Link created by "tfs env".
+	if err := AppFs.RemoveAll(r.versionBinDir()); err != nil {
+		logger.Warn("Failed to remove Terraform version directory", "error", err)
+	}
 
 	// Keep the in-memory cache consistent with disk.
 	delete(r.parentCache.releases, r.Version.String())
+	r.forget()
 
 	return nil
 }
 
+// VerifyChecksum makes sure the cached binary matches the checksum
+// recorded when it was installed.
+func (r *release) VerifyChecksum() error {
+	target := filepath.Join(r.parentCache.directory, r.fileName)
+
+	logger := slog.With(
+		"version", r.Version.String(),
+		"fileName", target,
+	)
+
+	sums, err := readChecksumFile(r.checksumPath())
+	if err != nil {
+		logger.Error("Failed to read Terraform binary checksum", "error", err)
+		return err
+	}
+	expected, ok := sums[r.fileName]
+	if !ok {
+		// Binaries installed by older versions of tfs.
+		logger.Warn("No checksum recorded for Terraform binary")
+		return nil
+	}
+
+	actual, err := sha256File(target)
+	if err != nil {
+		logger.Error("Failed to compute Terraform binary checksum", "error", err)
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, r.fileName, expected, actual)
+	}
+
+	return nil
+}
+
+// VerifyArchiveChecksum makes sure the cached binary was extracted from
+// the release archive with the given checksum.
+func (r *release) VerifyArchiveChecksum(expected string) error {
+	sums, err := readChecksumFile(r.checksumPath())
+	if err != nil {
+		slog.Error("Failed to read Terraform binary checksum", "error", err, "version", r.Version.String())
+		return err
+	}
+
+	fileName := archiveName(r.Version)
+	actual, ok := sums[fileName]
+	if !ok {
+		return fmt.Errorf("no archive checksum recorded for version %s; remove it from the cache and install it again", r.Version)
+	}
+	if actual != expected {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, fileName, expected, actual)
+	}
+
+	return nil
+}
+
+// path returns the path of the Terraform binary.
+func (r *release) path() string {
+	return filepath.Join(r.parentCache.directory, r.fileName)
+}
+
+// checksumPath returns the path of the file holding the release checksums.
+func (r *release) checksumPath() string {
+	return filepath.Join(r.parentCache.directory, r.fileName+checksumFileSuffix)
+}
+
 // Size function returns the size of the Terraform binary.
 func (r *release) Size() (uint64, error) {
 	target := filepath.Join(r.parentCache.directory, r.fileName)
@@ -179,6 +378,27 @@ func (r *release) Size() (uint64, error) {
 	return uint64(fi.Size()), nil
 }
 
+// info describes the release, as reported by the "list" command.
+func (r *release) info() (ReleaseInfo, error) {
+	size, err := r.Size()
+	if err != nil {
+		return ReleaseInfo{}, err
+	}
+
+	rm := r.metadata()
+
+	return ReleaseInfo{
+		Version:     r.Version.String(),
+		Size:        size,
+		InstalledAt: rm.InstalledAt,
+		LastUsed:    rm.LastUsed,
+		Active:      r.SameAs(r.parentCache.activeRelease),
+		Pinned:      r.isPinned(),
+		Source:      rm.Source,
+		Projects:    rm.Projects,
+	}, nil
+}
+
 // SameAs compares the current release and the given release.
 func (r *release) SameAs(ref *release) bool {
 	if r == nil || ref == nil {
@@ -1,6 +1,9 @@
 package tfs
 
 import (
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -95,3 +98,143 @@ func TestReleaseRemoveDeletesBinaryAndSymlink(t *testing.T) {
 		t.Errorf("Expected symlink %s to be deleted", symlink)
 	}
 }
+
+func TestReleaseInstallUsesSource(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	source := newFakeSource("1.10.0")
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(source)
+
+	release := cache.NewRelease(mustVersion(t, "1.10.0"))
+
+	if err := release.Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+
+	b, err := afero.ReadFile(AppFs, filepath.Join(cacheDir, release.fileName))
+	if err != nil {
+		t.Fatalf("Installed binary not found: %v", err)
+	}
+	if string(b) != "terraform 1.10.0" {
+		t.Errorf("Unexpected binary content %q", b)
+	}
+	if len(source.fetched) != 1 {
+		t.Errorf("Expected one download, got %d", len(source.fetched))
+	}
+}
+
+func TestReleaseInstallRecordsChecksum(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.10.0"))
+
+	release := cache.NewRelease(mustVersion(t, "1.10.0"))
+
+	if err := release.Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+
+	sums, err := readChecksumFile(release.checksumPath())
+	if err != nil {
+		t.Fatalf("Failed to read checksum file: %v", err)
+	}
+	if sums[release.fileName] != sha256Sum([]byte("terraform 1.10.0")) {
+		t.Errorf("Unexpected binary checksum %q", sums[release.fileName])
+	}
+	if _, ok := sums[archiveName(release.Version)]; !ok {
+		t.Errorf("Expected archive checksum to be recorded")
+	}
+
+	// Checksum files must not be mistaken for releases.
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+	if len(cache.releases) != 1 {
+		t.Errorf("Expected 1 release in cache, got %d", len(cache.releases))
+	}
+}
+
+func TestReleaseActivateRefusesTamperedBinary(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.10.0"))
+
+	release := cache.NewRelease(mustVersion(t, "1.10.0"))
+
+	if err := release.Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(cacheDir, release.fileName), []byte("malicious content"))
+
+	if err := release.Activate(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+
+	symlink := filepath.Join(viper.GetString("user_bin_directory"), "terraform")
+	if _, err := os.Lstat(symlink); err == nil {
+		t.Errorf("Expected symlink %s not to be created", symlink)
+	}
+}
+
+// interruptedSource writes part of the binary, then fails.
+type interruptedSource struct {
+	*fakeSource
+}
+
+func (s *interruptedSource) Fetch(ctx context.Context, v *version.Version, w io.Writer) (*Artifact, error) {
+	w.Write([]byte("partial"))
+	return nil, context.Canceled
+}
+
+func TestReleaseInstallInterrupted(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(&interruptedSource{newFakeSource("1.10.0")})
+
+	release := cache.NewRelease(mustVersion(t, "1.10.0"))
+
+	if err := release.Install(); err == nil {
+		t.Fatalf("Expected Install() to fail")
+	}
+
+	files, err := afero.ReadDir(AppFs, cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to read cache directory: %v", err)
+	}
+	for _, fi := range files {
+		if !fi.IsDir() && fi.Name() != lockFileName {
+			t.Errorf("Expected no file to be left behind, found %s", fi.Name())
+		}
+	}
+}
+
+func TestReleaseInstallPermissions(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newFakeSource("1.10.0"))
+
+	release := cache.NewRelease(mustVersion(t, "1.10.0"))
+
+	if err := release.Install(); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+
+	fi, err := AppFs.Stat(filepath.Join(cacheDir, release.fileName))
+	if err != nil {
+		t.Fatalf("Installed binary not found: %v", err)
+	}
+	if fi.Mode().Perm() != 0755 {
+		t.Errorf("Expected mode 0755, got %v", fi.Mode().Perm())
+	}
+}
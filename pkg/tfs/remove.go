@@ -0,0 +1,106 @@
+package tfs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
+
+// RemoveOptions selects the releases removed by Remove. Releases must
+// match all the given criteria.
+type RemoveOptions struct {
+	// Versions to remove, any when empty.
+	Versions []*version.Version
+
+	// Version constraint the releases must satisfy, e.g. ">= 1.3, < 1.5".
+	Constraint string
+
+	// Only remove alpha, beta and rc versions.
+	Prereleases bool
+
+	// Only remove releases that were not activated or run for this long.
+	UnusedSince time.Duration
+
+	// Also remove the active release.
+	Force bool
+
+	// Also remove pinned releases.
+	IncludePinned bool
+}
+
+// Remove removes the cached releases selected by the given options.
+func (c *LocalCache) Remove(opts RemoveOptions) (*PruneReport, error) {
+	if len(opts.Versions) == 0 && opts.Constraint == "" && !opts.Prereleases && opts.UnusedSince == 0 {
+		return nil, errors.New("no release selected; give versions, or use --constraint, --prereleases or --unused-since")
+	}
+
+	var constraint version.Constraints
+
+	if opts.Constraint != "" {
+		var err error
+		if constraint, err = version.NewConstraint(opts.Constraint); err != nil {
+			slog.Error("Failed to parse Terraform version constraint", "error", err, "constraint", opts.Constraint)
+			return nil, err
+		}
+	}
+
+	for _, v := range opts.Versions {
+		if _, ok := c.releases[v.String()]; !ok {
+			slog.Warn("Version is not in cache", "version", v.String())
+		}
+	}
+
+	cutoff := time.Now().Add(-opts.UnusedSince)
+
+	return c.removeReleases(opts.IncludePinned, func(r *release) bool {
+		if len(opts.Versions) > 0 && !slices.ContainsFunc(opts.Versions, r.Version.Equal) {
+			return false
+		}
+		if opts.Prereleases && r.Version.Prerelease() == "" {
+			return false
+		}
+		// Pre-releases never satisfy a constraint that does not
+		// mention one, so we check their core version instead.
+		if constraint != nil && !constraint.Check(r.Version) && !(opts.Prereleases && constraint.Check(r.Version.Core())) {
+			return false
+		}
+		if opts.UnusedSince > 0 && r.lastUse().After(cutoff) {
+			return false
+		}
+		if r.SameAs(c.activeRelease) && !opts.Force {
+			slog.Warn("Keeping active release; use --force to remove it", "version", r.Version.String())
+			return false
+		}
+		return true
+	})
+}
+
+// ParseAge parses a duration such as "90d", "2w" or "36h".
+func ParseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
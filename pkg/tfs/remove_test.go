@@ -0,0 +1,168 @@
+package tfs
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/afero"
+)
+
+// removedVersions returns the versions listed in a removal report.
+func removedVersions(report *PruneReport) []string {
+	var versions []string
+	for _, info := range report.Removed {
+		versions = append(versions, info.Version)
+	}
+	return versions
+}
+
+func TestCacheRemove(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     RemoveOptions
+		expected []string
+	}{
+		{
+			"versions",
+			RemoveOptions{Versions: []*version.Version{version.Must(version.NewVersion("1.5.0")), version.Must(version.NewVersion("1.5.1"))}},
+			[]string{"1.5.0", "1.5.1"},
+		},
+		{
+			"constraint",
+			RemoveOptions{Constraint: ">= 1.3, < 1.5.1"},
+			[]string{"1.3.0", "1.5.0"},
+		},
+		{
+			"prereleases",
+			RemoveOptions{Prereleases: true},
+			[]string{"1.6.0-beta1"},
+		},
+		{
+			"prereleases satisfying constraint",
+			RemoveOptions{Prereleases: true, Constraint: ">= 1.6"},
+			[]string{"1.6.0-beta1"},
+		},
+		{
+			"versions satisfying constraint",
+			RemoveOptions{Versions: []*version.Version{version.Must(version.NewVersion("1.3.0"))}, Constraint: ">= 1.5"},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir, cleanup := initTestFS(t)
+			defer cleanup()
+
+			cache := newPinTestCache(t, cacheDir, "1.3.0", "1.5.0", "1.5.1", "1.6.0-beta1")
+
+			report, err := cache.Remove(tt.opts)
+			if err != nil {
+				t.Fatalf("Cache.Remove() failed: %v", err)
+			}
+
+			if removed := removedVersions(report); !slices.Equal(removed, tt.expected) {
+				t.Errorf("Expected %v to be removed, got %v", tt.expected, removed)
+			}
+			for _, v := range []string{"1.3.0", "1.5.0", "1.5.1", "1.6.0-beta1"} {
+				exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+v))
+				if exists == slices.Contains(tt.expected, v) {
+					t.Errorf("Unexpected presence of %s in cache: %v", v, exists)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheRemoveKeepsActiveRelease(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := newPinTestCache(t, cacheDir, "1.5.0", "1.5.1")
+	if err := cache.releases["1.5.1"].Activate(); err != nil {
+		t.Fatalf("Release.Activate() failed: %v", err)
+	}
+
+	report, err := cache.Remove(RemoveOptions{Constraint: ">= 1.5"})
+	if err != nil {
+		t.Fatalf("Cache.Remove() failed: %v", err)
+	}
+	if removed := removedVersions(report); !slices.Equal(removed, []string{"1.5.0"}) {
+		t.Errorf("Expected only 1.5.0 to be removed, got %v", removed)
+	}
+
+	report, err = cache.Remove(RemoveOptions{Constraint: ">= 1.5", Force: true})
+	if err != nil {
+		t.Fatalf("Cache.Remove() failed: %v", err)
+	}
+	if removed := removedVersions(report); !slices.Equal(removed, []string{"1.5.1"}) {
+		t.Errorf("Expected active 1.5.1 to be removed, got %v", removed)
+	}
+	if report.ReclaimedBytes != uint64(len("terraform 1.5.1")) {
+		t.Errorf("Unexpected reclaimed space %d", report.ReclaimedBytes)
+	}
+}
+
+func TestCacheRemoveUnusedSince(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := newPinTestCache(t, cacheDir, "1.5.0", "1.5.1")
+
+	lastUsed := time.Now().Add(-100 * 24 * time.Hour)
+	cache.updateMetadata(func(m *cacheMetadata) {
+		m.Releases["1.5.0"].LastUsed = &lastUsed
+	})
+
+	report, err := cache.Remove(RemoveOptions{UnusedSince: 90 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Cache.Remove() failed: %v", err)
+	}
+	if removed := removedVersions(report); !slices.Equal(removed, []string{"1.5.0"}) {
+		t.Errorf("Expected only 1.5.0 to be removed, got %v", removed)
+	}
+}
+
+func TestCacheRemoveRequiresSelection(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := newPinTestCache(t, cacheDir, "1.5.0")
+
+	if _, err := cache.Remove(RemoveOptions{Force: true}); err == nil {
+		t.Errorf("Expected removal without criteria to fail")
+	}
+	if _, err := cache.Remove(RemoveOptions{Constraint: "not a constraint"}); err == nil {
+		t.Errorf("Expected invalid constraint to fail")
+	}
+	if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.5.0")); !exists {
+		t.Errorf("Expected 1.5.0 to remain")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90d": 90 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+		"0d":  0,
+	}
+	for value, expected := range tests {
+		d, err := ParseAge(value)
+		if err != nil {
+			t.Errorf("ParseAge(%q) failed: %v", value, err)
+		}
+		if d != expected {
+			t.Errorf("ParseAge(%q): expected %v, got %v", value, expected, d)
+		}
+	}
+
+	for _, value := range []string{"", "d", "-3d", "soon"} {
+		if _, err := ParseAge(value); err == nil {
+			t.Errorf("Expected ParseAge(%q) to fail", value)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package tfs
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Selection is the Terraform version selected for the current directory.
+type Selection struct {
+	Version *version.Version
+
+	// Project version requirement, if any.
+	Requirement *Requirement
+
+	// Project lock file the version was read from, if any.
+	Lock *ProjectLock
+}
+
+// SelectVersion selects the Terraform version to use in the current
+// directory. The given expression (a version, a constraint or a "latest"
+// expression) wins when not empty. Otherwise, the project lock file wins
+// over the project version requirement, unless the requirement comes from
+// the TFS_TERRAFORM_VERSION environment variable. Returns nil, nil if the
+// project does not require any version.
+func (c *LocalCache) SelectVersion(expr string) (*Selection, error) {
+	if expr != "" {
+		v, err := c.Resolve(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Selection{Version: v}, nil
+	}
+
+	req, err := FindVersionRequirement()
+	if err != nil {
+		return nil, err
+	}
+	if req != nil {
+		slog.Info("Found version requirement", "expression", req.Expression, "source", req.Source, "fileName", req.File)
+	}
+
+	sel := &Selection{Requirement: req}
+
+	if req == nil || req.Source != SourceEnvironment {
+		if sel.Lock, err = FindProjectLock(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case sel.Lock != nil:
+		if err := sel.Lock.Check(req); err != nil {
+			return sel, err
+		}
+		sel.Version = sel.Lock.TerraformVersion()
+		slog.Info("Using locked version", "version", sel.Version.String(), "fileName", sel.Lock.Path())
+	case req != nil:
+		if sel.Version, err = c.ResolveRequirement(req); err != nil {
+			return sel, err
+		}
+	default:
+		return nil, nil
+	}
+
+	return sel, nil
+}
+
+// InstallSelection makes sure the selected Terraform version is in the
+// cache. When the version comes from a lock file, the binary must have
+// been extracted from the locked release archive.
+func (c *LocalCache) InstallSelection(sel *Selection) (*release, error) {
+	r := c.NewRelease(sel.Version)
+
+	if err := r.Install(); err != nil {
+		return nil, err
+	}
+
+	if sel.Lock != nil {
+		hash, err := sel.Lock.Hash()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.VerifyArchiveChecksum(hash); err != nil {
+			slog.Error("Terraform binary does not match lock file", "error", err, "fileName", sel.Lock.Path())
+			return nil, err
+		}
+	}
+
+	// Remember which projects use the release.
+	switch {
+	case sel.Lock != nil:
+		r.recordProject(filepath.Dir(sel.Lock.Path()))
+	case sel.Requirement != nil && sel.Requirement.Directory != "":
+		r.recordProject(sel.Requirement.Directory)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,289 @@
+package tfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// Activation modes.
+const (
+	// The terraform symlink points to the active cached binary.
+	ActivationModeSymlink = "symlink"
+
+	// The terraform symlink points to tfs, which runs the binary
+	// required by the current directory.
+	ActivationModeShim = "shim"
+)
+
+// Name of the file caching the versions selected by the shim, by directory.
+const shimCacheFileName = "shim-cache.json"
+
+// Replaced in tests.
+var (
+	defaultExecFunc = syscall.Exec
+	execFunc        = defaultExecFunc
+)
+
+// shimCacheEntry is the version selected by the shim for a directory.
+type shimCacheEntry struct {
+	Version string `json:"version"`
+
+	// Modification times of the files and directories the selection
+	// depends on, zero for missing ones.
+	Stamps map[string]int64 `json:"stamps"`
+
+	// Size and modification time of the binary when its checksum was
+	// last verified.
+	BinarySize  int64 `json:"binarySize"`
+	BinaryStamp int64 `json:"binaryStamp"`
+
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// InstallShim points the terraform symlink to the tfs executable, so that
+// running terraform selects the binary required by the current directory.
+func (c *LocalCache) InstallShim() error {
+	var (
+		userBinDir = viper.GetString("user_bin_directory")
+		symlink    = filepath.Join(userBinDir, "terraform")
+	)
+
+	logger := slog.With("symlink", symlink)
+
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		logger.Error("Failed to locate tfs executable", "error", err)
+		return err
+	}
+	logger = logger.With("target", executable)
+
+	if target, ok, _ := AppFs.EvalSymlinksIfPossible(symlink); ok && target == executable {
+		logger.Info("Shim is already installed")
+		return nil
+	}
+
+	// Never overwrite a terraform binary that tfs did not install.
+	if err := c.protectExistingTerraform(symlink); err != nil {
+		logger.Error("Refusing to replace existing terraform binary", "error", err)
+		return err
+	}
+
+	// Remove the link if it exists.
+	if _, b, err := AppFs.LstatIfPossible(symlink); !b {
+		logger.Warn("The operating system does not seem to support `os.Lstat`", "error", err)
+	} else if err == nil {
+		AppFs.Remove(symlink)
+	}
+
+	if err := AppFs.SymlinkIfPossible(executable, symlink); err != nil {
+		logger.Error("Failed to create symlink", "error", err)
+		return err
+	}
+
+	c.activeRelease = nil
+	logger.Info("Installed terraform shim")
+
+	return nil
+}
+
+// RunShim runs the Terraform binary required by the current directory with
+// the given arguments, in place of the current process. The selected version
+// is cached until one of the files it was selected from changes, or until
+// "shim_cache_ttl" expires. Missing versions are installed when
+// "shim_auto_install" is set.
+func (c *LocalCache) RunShim(args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		slog.Error("Failed to get working directory", "error", err)
+		return err
+	}
+	key := cwd + "\x00" + os.Getenv(SourceEnvironment)
+
+	entries := c.readShimCache()
+
+	// Fast path.
+	if entry, ok := entries[key]; ok && entry.valid() {
+		if v, err := version.NewVersion(entry.Version); err == nil {
+			r := c.NewRelease(v)
+			if fi, err := AppFs.Stat(r.path()); err == nil {
+				// Only hash the binary again when it changed.
+				if !entry.verified(fi) {
+					if err := r.VerifyChecksum(); err != nil {
+						return err
+					}
+					entries[key] = entry.withBinary(fi)
+					c.writeShimCache(entries)
+				}
+				return r.exec(args)
+			}
+		}
+	}
+
+	if err := c.Load(); err != nil {
+		return err
+	}
+
+	sel, err := c.SelectVersion("")
+	if err != nil {
+		return err
+	}
+
+	var r *release
+
+	switch {
+	case sel != nil:
+		if _, cached := c.releases[sel.Version.String()]; !cached && !viper.GetBool("shim_auto_install") {
+			return fmt.Errorf("Terraform %s is not installed; run 'tfs' to install it", sel.Version)
+		}
+		if r, err = c.InstallSelection(sel); err != nil {
+			return err
+		}
+	case !c.IsEmpty():
+		// Not cached, as it depends on the cache contents.
+		r = c.LastRelease
+	default:
+		return errors.New("no Terraform version found; run 'tfs <version>' to install one")
+	}
+
+	if err := r.VerifyChecksum(); err != nil {
+		return err
+	}
+
+	if sel != nil {
+		entry := shimCacheEntry{
+			Version:   sel.Version.String(),
+			Stamps:    fileStamps(selectionFiles(cwd, sel)),
+			ExpiresAt: time.Now().Add(viper.GetDuration("shim_cache_ttl")),
+		}
+		if fi, err := AppFs.Stat(r.path()); err == nil {
+			entry = entry.withBinary(fi)
+		}
+		entries[key] = entry
+		c.writeShimCache(entries)
+	}
+
+	return r.exec(args)
+}
+
+// exec replaces the current process with the Terraform binary. Standard
+// streams, environment, signals and exit status are those of the process.
+func (r *release) exec(args []string) error {
+	argv := append([]string{terraformProductName}, args...)
+
+	r.recordUse()
+
+	if err := execFunc(r.path(), argv, os.Environ()); err != nil {
+		slog.Error("Failed to run Terraform", "error", err, "fileName", r.path())
+		return err
+	}
+
+	return nil
+}
+
+// selectionFiles returns the files and directories a version selection
+// depends on: adding or removing a file in any of the searched directories,
+// or editing the files the version was read from, may change it.
+func selectionFiles(cwd string, sel *Selection) []string {
+	files := searchDirectories(cwd)
+
+	if req := sel.Requirement; req != nil {
+		if req.File != "" {
+			files = append(files, req.File)
+		}
+		for _, vc := range req.Constraints {
+			files = append(files, vc.File, filepath.Dir(vc.File))
+		}
+	}
+	if sel.Lock != nil {
+		files = append(files, sel.Lock.Path())
+	}
+
+	return files
+}
+
+// fileStamps returns the modification times of the given files.
+func fileStamps(files []string) map[string]int64 {
+	stamps := make(map[string]int64, len(files))
+	for _, file := range files {
+		stamps[file] = 0
+		if fi, err := os.Stat(file); err == nil {
+			stamps[file] = fi.ModTime().UnixNano()
+		}
+	}
+	return stamps
+}
+
+// valid tells whether the cached selection can still be used.
+func (e shimCacheEntry) valid() bool {
+	if time.Now().After(e.ExpiresAt) {
+		return false
+	}
+
+	files := make([]string, 0, len(e.Stamps))
+	for file := range e.Stamps {
+		files = append(files, file)
+	}
+	for file, stamp := range fileStamps(files) {
+		if e.Stamps[file] != stamp {
+			return false
+		}
+	}
+
+	return true
+}
+
+// verified tells whether the binary is the one that was verified when
+// the entry was saved.
+func (e shimCacheEntry) verified(fi os.FileInfo) bool {
+	return e.BinaryStamp != 0 && fi.Size() == e.BinarySize && fi.ModTime().UnixNano() == e.BinaryStamp
+}
+
+// withBinary returns the entry with the size and modification time of a
+// freshly verified binary.
+func (e shimCacheEntry) withBinary(fi os.FileInfo) shimCacheEntry {
+	e.BinarySize = fi.Size()
+	e.BinaryStamp = fi.ModTime().UnixNano()
+	return e
+}
+
+// readShimCache reads the shim resolution cache, ignoring errors.
+func (c *LocalCache) readShimCache() map[string]shimCacheEntry {
+	entries := make(map[string]shimCacheEntry)
+
+	if b, err := afero.ReadFile(AppFs, filepath.Join(c.directory, shimCacheFileName)); err == nil {
+		json.Unmarshal(b, &entries)
+	}
+
+	return entries
+}
+
+// writeShimCache saves the shim resolution cache, without the expired
+// entries. Errors are only logged, as the cache is an optimization.
+func (c *LocalCache) writeShimCache(entries map[string]shimCacheEntry) {
+	for key, entry := range entries {
+		if time.Now().After(entry.ExpiresAt) {
+			delete(entries, key)
+		}
+	}
+
+	b, err := json.Marshal(entries)
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(c.directory, shimCacheFileName), b, 0644)
+	}
+	if err != nil {
+		slog.Warn("Failed to save shim resolution cache", "error", err)
+	}
+}
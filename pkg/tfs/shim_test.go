@@ -0,0 +1,157 @@
+package tfs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// stubExec records the programs run instead of replacing the process.
+func stubExec(t *testing.T) *[][]string {
+	t.Helper()
+
+	var calls [][]string
+	execFunc = func(path string, argv []string, env []string) error {
+		calls = append(calls, append([]string{path}, argv...))
+		return nil
+	}
+	t.Cleanup(func() { execFunc = defaultExecFunc })
+
+	return &calls
+}
+
+func TestRunShim(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	calls := stubExec(t)
+
+	writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.5.2"), []byte("dummy content"))
+
+	dir := t.TempDir()
+	writeProjectFile(t, dir, ".terraform-version", "1.5.7")
+
+	t.Chdir(dir)
+	t.Setenv(SourceEnvironment, "")
+	viper.Set("version_search_boundary", dir)
+	viper.Set("shim_auto_install", true)
+	viper.Set("shim_cache_ttl", time.Hour)
+
+	source := newFakeSource("1.5.2", "1.5.7", "1.6.3")
+	runShim := func(args ...string) []string {
+		t.Helper()
+		cache := NewLocalCache(cacheDir)
+		cache.SetSource(source)
+		if err := cache.RunShim(args); err != nil {
+			t.Fatalf("RunShim() failed: %v", err)
+		}
+		return (*calls)[len(*calls)-1]
+	}
+
+	t.Run("installs and runs the required version", func(t *testing.T) {
+		call := runShim("plan", "-out=plan")
+		expected := []string{filepath.Join(cacheDir, testFilePrefix+"1.5.7"), "terraform", "plan", "-out=plan"}
+		if !reflect.DeepEqual(call, expected) {
+			t.Fatalf("expected %v, got %v", expected, call)
+		}
+		if !reflect.DeepEqual(source.fetched, []string{"1.5.7"}) {
+			t.Fatalf("expected 1.5.7 to be installed, got %v", source.fetched)
+		}
+	})
+
+	t.Run("uses the resolution cache", func(t *testing.T) {
+		path := filepath.Join(cacheDir, shimCacheFileName)
+		b, err := afero.ReadFile(AppFs, path)
+		if err != nil {
+			t.Fatalf("Failed to read shim cache: %v", err)
+		}
+		var entries map[string]shimCacheEntry
+		if err := json.Unmarshal(b, &entries); err != nil || len(entries) != 1 {
+			t.Fatalf("unexpected shim cache contents: %s", b)
+		}
+		// Make the cached selection recognizable.
+		for key, entry := range entries {
+			entry.Version = "1.5.2"
+			entries[key] = entry
+		}
+		b, _ = json.Marshal(entries)
+		writeTestFile(t, path, b)
+
+		if call := runShim("version"); call[0] != filepath.Join(cacheDir, testFilePrefix+"1.5.2") {
+			t.Fatalf("expected cached selection to be used, got %v", call)
+		}
+	})
+
+	t.Run("verifies cached selection checksum", func(t *testing.T) {
+		binary := filepath.Join(cacheDir, testFilePrefix+"1.5.2")
+		sums := binary + checksumFileSuffix
+		writeTestFile(t, sums, []byte(Checksums{testFilePrefix + "1.5.2": sha256Sum([]byte("other content"))}.String()))
+		defer AppFs.Remove(sums)
+
+		// The binary did not change since it was last verified.
+		runShim("version")
+
+		writeTestFile(t, binary, []byte("tampered content"))
+		defer writeTestFile(t, binary, []byte("dummy content"))
+
+		cache := NewLocalCache(cacheDir)
+		cache.SetSource(source)
+		if err := cache.RunShim(nil); !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+		}
+	})
+
+	t.Run("version file changed", func(t *testing.T) {
+		writeProjectFile(t, dir, ".terraform-version", "1.6.3")
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(filepath.Join(dir, ".terraform-version"), future, future)
+
+		if call := runShim("version"); call[0] != filepath.Join(cacheDir, testFilePrefix+"1.6.3") {
+			t.Fatalf("expected 1.6.3 to be selected, got %v", call)
+		}
+	})
+
+	t.Run("auto install disabled", func(t *testing.T) {
+		viper.Set("shim_auto_install", false)
+		t.Setenv(SourceEnvironment, "1.5.8")
+
+		cache := NewLocalCache(cacheDir)
+		cache.SetSource(newFakeSource("1.5.8"))
+		if err := cache.RunShim(nil); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestInstallShim(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	if err := cache.InstallShim(); err != nil {
+		t.Fatalf("InstallShim() failed: %v", err)
+	}
+
+	executable, _ := os.Executable()
+	executable, _ = filepath.EvalSymlinks(executable)
+
+	target, err := os.Readlink(filepath.Join(viper.GetString("user_bin_directory"), "terraform"))
+	if err != nil {
+		t.Fatalf("Failed to read symlink: %v", err)
+	}
+	if target != executable {
+		t.Fatalf("expected symlink to point to %s, got %s", executable, target)
+	}
+
+	// Idempotent.
+	if err := cache.InstallShim(); err != nil {
+		t.Fatalf("InstallShim() failed: %v", err)
+	}
+}
@@ -0,0 +1,458 @@
+package tfs
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Default location of the HashiCorp releases API.
+	defaultReleasesURL = "https://releases.hashicorp.com"
+
+	// Product and binary name in release archives.
+	terraformProductName = "terraform"
+)
+
+// Source is the place Terraform binaries are fetched from.
+type Source interface {
+	// Name returns a short description of the source, used in logs.
+	Name() string
+
+	// Versions returns all the Terraform versions published by the source.
+	Versions(ctx context.Context) ([]*version.Version, error)
+
+	// Fetch retrieves the Terraform binary for the given version
+	// and writes its contents to w.
+	Fetch(ctx context.Context, v *version.Version, w io.Writer) (*Artifact, error)
+
+	// Checksums returns the verified contents of the SHA256SUMS
+	// file published for the given version.
+	Checksums(ctx context.Context, v *version.Version) (Checksums, error)
+}
+
+// Artifact describes the release archive a Terraform binary was extracted from.
+type Artifact struct {
+	// Location of the archive.
+	URL string
+
+	// Archive file name, as listed in the SHA256SUMS file.
+	FileName string
+
+	// Hex-encoded SHA256 sum of the archive.
+	SHA256 string
+
+	// Whether the archive was checked against signed checksums.
+	Verified bool
+}
+
+// NewSource creates the release source selected in configuration.
+func NewSource() (Source, error) {
+	// Offline mode takes precedence over the configured source
+	// so that we never try to reach the network.
+	if viper.GetBool("offline") {
+		directory := viper.GetString("offline_source_directory")
+		if directory == "" {
+			return nil, errors.New("offline mode requires the 'offline_source_directory' setting")
+		}
+		return &directorySource{directory: directory}, nil
+	}
+
+	kind := viper.GetString("release_source")
+
+	switch kind {
+	case "", "hashicorp":
+		return &releasesSource{baseURL: defaultReleasesURL}, nil
+	case "mirror":
+		baseURL := viper.GetString("release_source_url")
+		if baseURL == "" {
+			return nil, fmt.Errorf("release source %q requires the 'release_source_url' setting", kind)
+		}
+		return &releasesSource{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	default:
+		return nil, fmt.Errorf("unknown release source %q", kind)
+	}
+}
+
+// Shared by the requests made to release sources. The overall
+// request timeout is set by "release_source_timeout".
+var httpTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 30 * time.Second,
+	IdleConnTimeout:       90 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// releasesSource fetches Terraform from the HashiCorp releases API,
+// or from any mirror that follows the same directory layout.
+type releasesSource struct {
+	baseURL string
+}
+
+func (s *releasesSource) Name() string {
+	return s.baseURL
+}
+
+// Versions reads the product index published by the releases API.
+func (s *releasesSource) Versions(ctx context.Context) ([]*version.Version, error) {
+	var index struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	if err := s.getJSON(ctx, s.url("index.json"), &index); err != nil {
+		return nil, err
+	}
+
+	versions := make([]*version.Version, 0, len(index.Versions))
+	for raw := range index.Versions {
+		v, err := version.NewVersion(raw)
+		if err != nil {
+			// Skip unparseable versions.
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// Fetch downloads the release archive for the current platform, checks
+// it against the signed checksums and extracts the Terraform binary.
+func (s *releasesSource) Fetch(ctx context.Context, v *version.Version, w io.Writer) (*Artifact, error) {
+	sums, err := s.Checksums(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := archiveName(v)
+	expected, ok := sums[fileName]
+	if !ok {
+		return nil, fmt.Errorf("no checksum found for %q", fileName)
+	}
+
+	archiveURL := s.url(v.String(), fileName)
+	archive, err := s.download(ctx, archiveURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		archive.Close()
+		AppFs.Remove(archive.Name())
+	}()
+
+	sum, size, err := sha256Reader(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := &Artifact{
+		URL:      archiveURL,
+		FileName: fileName,
+		SHA256:   sum,
+		Verified: true,
+	}
+	if artifact.SHA256 != expected {
+		return nil, fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, fileName, expected, artifact.SHA256)
+	}
+
+	return artifact, unzipBinary(archive, size, w)
+}
+
+// Checksums downloads the SHA256SUMS file of the given version
+// and verifies its signature.
+func (s *releasesSource) Checksums(ctx context.Context, v *version.Version) (Checksums, error) {
+	var index struct {
+		SHASUMS     string   `json:"shasums"`
+		SHASUMSSig  string   `json:"shasums_signature"`
+		SHASUMSSigs []string `json:"shasums_signatures"`
+	}
+	if err := s.getJSON(ctx, s.url(v.String(), "index.json"), &index); err != nil {
+		return nil, err
+	}
+
+	// The unsuffixed signature is made with the HashiCorp key,
+	// which mirrors are expected to keep as is.
+	sigFileName := index.SHASUMSSig
+	if sigFileName == "" {
+		for _, name := range index.SHASUMSSigs {
+			if strings.HasSuffix(name, "_SHA256SUMS.sig") {
+				sigFileName = name
+			}
+		}
+	}
+	if index.SHASUMS == "" || sigFileName == "" {
+		return nil, fmt.Errorf("no signed checksums published for version %s", v)
+	}
+
+	sums, err := s.get(ctx, s.url(v.String(), index.SHASUMS))
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.get(ctx, s.url(v.String(), sigFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	return verifyChecksums(sums, signature)
+}
+
+// url builds the address of a file in the product directory.
+func (s *releasesSource) url(elem ...string) string {
+	u := s.baseURL + "/" + terraformProductName
+	for _, e := range elem {
+		u += "/" + url.PathEscape(e)
+	}
+	return u
+}
+
+// open starts downloading the file at the given address.
+func (s *releasesSource) open(ctx context.Context, u string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Transport: httpTransport,
+		Timeout:   viper.GetDuration("release_source_timeout"),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %q: %s", u, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// get downloads the file at the given address.
+func (s *releasesSource) get(ctx context.Context, u string) ([]byte, error) {
+	body, err := s.open(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// download writes the file at the given address to a temporary file,
+// rewound and ready to be read. The caller must close and remove it.
+func (s *releasesSource) download(ctx context.Context, u string) (afero.File, error) {
+	body, err := s.open(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if err := AppFs.MkdirAll(os.TempDir(), os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := afero.TempFile(AppFs, os.TempDir(), "tfs-*.zip")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = io.Copy(f, body); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		AppFs.Remove(f.Name())
+		return nil, fmt.Errorf("failed to download %q: %w", u, err)
+	}
+
+	return f, nil
+}
+
+// getJSON downloads and decodes the JSON document at the given address.
+func (s *releasesSource) getJSON(ctx context.Context, u string, v any) error {
+	b, err := s.get(ctx, u)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to decode %q: %w", u, err)
+	}
+	return nil
+}
+
+// directorySource reads Terraform from a local directory holding the
+// official release archives (terraform_<version>_<os>_<arch>.zip).
+type directorySource struct {
+	directory string
+}
+
+func (s *directorySource) Name() string {
+	return s.directory
+}
+
+// Versions lists the archives available for the current platform.
+func (s *directorySource) Versions(ctx context.Context) ([]*version.Version, error) {
+	prefix, suffix := archiveAffixes()
+
+	files, err := afero.Glob(AppFs, filepath.Join(s.directory, prefix+"*"+suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*version.Version, 0, len(files))
+	for _, fileName := range files {
+		raw := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(fileName), prefix), suffix)
+		v, err := version.NewVersion(raw)
+		if err != nil {
+			// Skip unrelated archives.
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// Fetch extracts the Terraform binary from the release archive. The archive
+// is checked against the signed checksums that sit next to it, which may only
+// be missing when "offline_allow_unverified" is set.
+func (s *directorySource) Fetch(ctx context.Context, v *version.Version, w io.Writer) (*Artifact, error) {
+	fileName := archiveName(v)
+	archivePath := filepath.Join(s.directory, fileName)
+
+	archive, err := AppFs.Open(archivePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("archive %s not found in offline source directory %s", fileName, s.directory)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	sum, size, err := sha256Reader(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := &Artifact{
+		URL:      archivePath,
+		FileName: fileName,
+		SHA256:   sum,
+	}
+
+	sums, err := s.Checksums(ctx, v)
+	switch {
+	case os.IsNotExist(err) && viper.GetBool("offline_allow_unverified"):
+		slog.Warn("Checksums not found in offline source directory, archive cannot be verified",
+			"directory", s.directory,
+			"fileName", fileName,
+		)
+	case os.IsNotExist(err):
+		return nil, fmt.Errorf("signed checksums of %s not found in offline source directory %s; "+
+			"set 'offline_allow_unverified' to install it anyway", fileName, s.directory)
+	case err != nil:
+		return nil, err
+	case sums[fileName] != artifact.SHA256:
+		return nil, fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, fileName, sums[fileName], artifact.SHA256)
+	default:
+		artifact.Verified = true
+	}
+
+	return artifact, unzipBinary(archive, size, w)
+}
+
+// Checksums reads the SHA256SUMS file of the given version from the
+// directory and verifies its signature.
+func (s *directorySource) Checksums(ctx context.Context, v *version.Version) (Checksums, error) {
+	sumsPath := filepath.Join(s.directory, checksumsName(v))
+
+	sums, err := afero.ReadFile(AppFs, sumsPath)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := afero.ReadFile(AppFs, sumsPath+".sig")
+	if err != nil {
+		return nil, err
+	}
+
+	return verifyChecksums(sums, signature)
+}
+
+// archiveAffixes returns the prefix and suffix of the release
+// archive names for the current platform.
+func archiveAffixes() (string, string) {
+	return terraformProductName + "_", "_" + runtime.GOOS + "_" + runtime.GOARCH + ".zip"
+}
+
+// archiveName returns the name of the release archive
+// of the given version for the current platform.
+func archiveName(v *version.Version) string {
+	prefix, suffix := archiveAffixes()
+	return prefix + v.String() + suffix
+}
+
+// checksumsName returns the name of the SHA256SUMS file of the given version.
+func checksumsName(v *version.Version) string {
+	return terraformProductName + "_" + v.String() + "_SHA256SUMS"
+}
+
+// sha256Reader returns the hex-encoded SHA256 sum and the size of
+// the given file, rewound to be read again.
+func sha256Reader(f io.ReadSeeker) (string, int64, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// unzipBinary copies the Terraform binary from a release archive to w.
+func unzipBinary(r io.ReaderAt, size int64, w io.Writer) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		if zf.Name != terraformProductName {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		_, err = io.Copy(w, rc)
+		return err
+	}
+
+	return fmt.Errorf("archive does not contain the %q binary", terraformProductName)
+}
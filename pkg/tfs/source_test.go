@@ -0,0 +1,258 @@
+package tfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/viper"
+)
+
+func TestNewSource(t *testing.T) {
+	defer viper.Reset()
+
+	tests := []struct {
+		name        string
+		kind        string
+		url         string
+		expectedURL string
+		shouldError bool
+	}{
+		{
+			name:        "Default source",
+			kind:        "",
+			expectedURL: defaultReleasesURL,
+		},
+		{
+			name:        "HashiCorp source",
+			kind:        "hashicorp",
+			expectedURL: defaultReleasesURL,
+		},
+		{
+			name:        "Mirror source",
+			kind:        "mirror",
+			url:         "https://mirror.example.com/hashicorp/",
+			expectedURL: "https://mirror.example.com/hashicorp",
+		},
+		{
+			name:        "Mirror source without URL",
+			kind:        "mirror",
+			shouldError: true,
+		},
+		{
+			name:        "Unknown source",
+			kind:        "ftp",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("release_source", tt.kind)
+			viper.Set("release_source_url", tt.url)
+
+			s, err := NewSource()
+
+			if tt.shouldError {
+				if err == nil {
+					t.Fatalf("expected error, got source %s", s.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Name() != tt.expectedURL {
+				t.Errorf("expected source %s, got %s", tt.expectedURL, s.Name())
+			}
+		})
+	}
+}
+
+func TestReleasesSourceVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/terraform/index.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"terraform","versions":{"1.5.7":{},"1.6.0-beta1":{},"1.6.0":{},"not-a-version":{}}}`))
+	}))
+	defer server.Close()
+
+	s := &releasesSource{baseURL: server.URL}
+
+	versions, err := s.Versions(context.Background())
+	if err != nil {
+		t.Fatalf("Versions() failed: %v", err)
+	}
+	sort.Sort(version.Collection(versions))
+
+	expected := []string{"1.5.7", "1.6.0-beta1", "1.6.0"}
+	if len(versions) != len(expected) {
+		t.Fatalf("expected %d versions, got %d", len(expected), len(versions))
+	}
+	for i, v := range versions {
+		if v.String() != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], v.String())
+		}
+	}
+}
+
+func TestReleasesSourceVersionsError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	s := &releasesSource{baseURL: server.URL}
+
+	if _, err := s.Versions(context.Background()); err == nil {
+		t.Fatalf("expected error from a missing release index")
+	}
+}
+
+func TestReleasesSourceTimeout(t *testing.T) {
+	defer viper.Reset()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A mirror that never answers.
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	viper.Set("release_source_timeout", 100*time.Millisecond)
+
+	s := &releasesSource{baseURL: server.URL}
+
+	done := make(chan error)
+	go func() {
+		_, err := s.Versions(context.Background())
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected error from a stalled release source")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected request to a stalled release source to time out")
+	}
+}
+
+// writeTestArchive creates a release archive holding the given files.
+func writeTestArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create archive entry: %v", err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	if err := AppFs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create archive directory: %v", err)
+	}
+	writeTestFile(t, path, buf.Bytes())
+}
+
+func TestDirectorySource(t *testing.T) {
+	tempDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	dir := filepath.Join(tempDir, "archives")
+	prefix, suffix := archiveAffixes()
+
+	writeTestArchive(t, filepath.Join(dir, prefix+"1.5.7"+suffix), map[string]string{
+		"LICENSE.txt": "license",
+		"terraform":   "terraform 1.5.7",
+	})
+	writeTestArchive(t, filepath.Join(dir, prefix+"1.6.0"+suffix), map[string]string{
+		"LICENSE.txt": "license",
+	})
+	writeTestFile(t, filepath.Join(dir, prefix+"1.7.0_plan9_mips.zip"), []byte("other platform"))
+	writeTestFile(t, filepath.Join(dir, prefix+"1.7.0_SHA256SUMS"), []byte("checksums"))
+
+	viper.Set("offline", true)
+	viper.Set("offline_source_directory", dir)
+
+	s, err := NewSource()
+	if err != nil {
+		t.Fatalf("NewSource() failed: %v", err)
+	}
+
+	t.Run("versions", func(t *testing.T) {
+		versions, err := s.Versions(context.Background())
+		if err != nil {
+			t.Fatalf("Versions() failed: %v", err)
+		}
+		if len(versions) != 2 {
+			t.Errorf("expected 2 versions, got %d", len(versions))
+		}
+	})
+
+	t.Run("unverified archive", func(t *testing.T) {
+		_, err := s.Fetch(context.Background(), mustVersion(t, "1.5.7"), io.Discard)
+		if err == nil || !strings.Contains(err.Error(), "offline_allow_unverified") {
+			t.Errorf("expected missing checksums error, got %v", err)
+		}
+	})
+
+	t.Run("unverified archive allowed", func(t *testing.T) {
+		viper.Set("offline_allow_unverified", true)
+		defer viper.Set("offline_allow_unverified", false)
+
+		var buf bytes.Buffer
+		artifact, err := s.Fetch(context.Background(), mustVersion(t, "1.5.7"), &buf)
+		if err != nil {
+			t.Fatalf("Fetch() failed: %v", err)
+		}
+		if artifact.Verified {
+			t.Errorf("expected archive not to be verified")
+		}
+		if buf.String() != "terraform 1.5.7" {
+			t.Errorf("unexpected binary content %q", buf.String())
+		}
+	})
+
+	t.Run("binary missing from archive", func(t *testing.T) {
+		viper.Set("offline_allow_unverified", true)
+		defer viper.Set("offline_allow_unverified", false)
+
+		if _, err := s.Fetch(context.Background(), mustVersion(t, "1.6.0"), io.Discard); err == nil {
+			t.Errorf("expected error for an archive without binary")
+		}
+	})
+
+	t.Run("archive missing", func(t *testing.T) {
+		_, err := s.Fetch(context.Background(), mustVersion(t, "1.8.0"), io.Discard)
+		if err == nil || !strings.Contains(err.Error(), "not found in offline source directory") {
+			t.Errorf("expected missing archive error, got %v", err)
+		}
+	})
+}
+
+func TestNewSourceOfflineWithoutDirectory(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("offline", true)
+
+	if _, err := NewSource(); err == nil {
+		t.Fatalf("expected error when no offline source directory is configured")
+	}
+}
@@ -1,49 +1,258 @@
 package tfs
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/spf13/viper"
 )
 
-// GetTfVersionConstraint looks for a version constraint in Terraform manifest files
-// and returns the constraint string.
-func GetTfVersionConstraint() (string, error) {
-	path, err := os.Getwd()
+// Version resolution strategies.
+const (
+	ResolveCachedFirst = "cached-first"
+	ResolveRemoteFirst = "remote-first"
+	ResolveCachedOnly  = "cached-only"
+)
 
-	if err != nil {
-		slog.Error("Failed to get working directory", "error", err)
-		return "", err
+// ErrNoMatchingVersion is returned when none of the candidate
+// versions satisfies a version constraint.
+var ErrNoMatchingVersion = errors.New("no version satisfies constraint")
+
+// VersionConstraint is a required_version setting found in Terraform configuration.
+type VersionConstraint struct {
+	Constraint string
+	File       string
+	Line       int
+}
+
+func (vc VersionConstraint) String() string {
+	return fmt.Sprintf("%q (%s:%d)", vc.Constraint, vc.File, vc.Line)
+}
+
+// Schemas only used to locate required_version settings,
+// which are otherwise read by terraform-config-inspect.
+var (
+	configSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}},
 	}
+	terraformBlockSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "required_version"}},
+	}
+)
 
-	logger := slog.With("path", path)
+// moduleVersionConstraints returns all the required_version settings of the
+// Terraform configuration in the given directory, including those of the
+// local modules it calls (with a "./" or "../" source), recursively.
+func moduleVersionConstraints(path string) ([]VersionConstraint, error) {
+	return collectVersionConstraints(hclparse.NewParser(), filepath.Clean(path), make(map[string]bool))
+}
 
-	if !tfconfig.IsModuleDir(path) {
-		logger.Info("Terraform configuration not found (are you in a module folder?)")
-		return "", nil
+func collectVersionConstraints(parser *hclparse.Parser, dir string, visited map[string]bool) ([]VersionConstraint, error) {
+	if visited[dir] {
+		return nil, nil
 	}
+	visited[dir] = true
+
+	logger := slog.With("path", dir)
+
+	var (
+		constraints []VersionConstraint
+		module      = tfconfig.NewModule(dir)
+	)
 
-	module, diags := tfconfig.LoadModule(path)
-	if diags.HasErrors() {
-		logger.Error("Failed to load Terraform configuration", "error", diags.Err())
-		return "", diags.Err()
+	for _, fileName := range configFiles(dir) {
+		var (
+			file  *hcl.File
+			diags hcl.Diagnostics
+		)
+		if strings.HasSuffix(fileName, ".json") {
+			file, diags = parser.ParseJSONFile(fileName)
+		} else {
+			file, diags = parser.ParseHCLFile(fileName)
+		}
+		if diags.HasErrors() {
+			logger.Error("Failed to load Terraform configuration", "error", diags)
+			return nil, diags
+		}
+
+		// Each file is also loaded on its own, to know
+		// where the required_version settings come from.
+		fileModule := tfconfig.NewModule(dir)
+		if diags := tfconfig.LoadModuleFromFile(file, fileModule); diags.HasErrors() {
+			logger.Error("Failed to load Terraform configuration", "error", diags)
+			return nil, diags
+		}
+		tfconfig.LoadModuleFromFile(file, module)
+
+		lines := requiredVersionLines(file)
+		fileConstraints := make([]VersionConstraint, 0, len(fileModule.RequiredCore))
+		for i, constraint := range fileModule.RequiredCore {
+			vc := VersionConstraint{Constraint: constraint, File: fileName}
+			if i < len(lines) {
+				vc.Line = lines[i]
+			}
+			fileConstraints = append(fileConstraints, vc)
+		}
+
+		// Like Terraform, override files replace the
+		// required_version settings of the module.
+		if isOverrideFile(fileName) && len(fileConstraints) > 0 {
+			constraints = fileConstraints
+		} else {
+			constraints = append(constraints, fileConstraints...)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(module.ModuleCalls)) {
+		source := module.ModuleCalls[name].Source
+		if !isLocalModuleSource(source) {
+			continue
+		}
+		moduleConstraints, err := collectVersionConstraints(parser, filepath.Join(dir, source), visited)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, moduleConstraints...)
 	}
 
-	// Get Terraform version constraint from current configuration.
-	if len(module.RequiredCore) != 0 {
-		return module.RequiredCore[0], nil
+	return constraints, nil
+}
+
+// requiredVersionLines returns the lines of the required_version
+// settings of the given file, in order of appearance.
+func requiredVersionLines(file *hcl.File) []int {
+	var lines []int
+
+	content, _, _ := file.Body.PartialContent(configSchema)
+	for _, block := range content.Blocks {
+		blockContent, _, _ := block.Body.PartialContent(terraformBlockSchema)
+		if attr, ok := blockContent.Attributes["required_version"]; ok {
+			lines = append(lines, attr.Range.Start.Line)
+		}
 	}
 
-	// No version defined in Terraform configuration.
-	return "", nil
+	return lines
+}
+
+// configFiles returns the Terraform configuration files in the given directory.
+func configFiles(dir string) []string {
+	var files, overrides []string
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "#") || strings.HasSuffix(name, "~") {
+			// Skip editor temporary files.
+			continue
+		}
+		if !strings.HasSuffix(name, ".tf") && !strings.HasSuffix(name, ".tf.json") {
+			continue
+		}
+		// Override files are processed last, as Terraform does.
+		if isOverrideFile(name) {
+			overrides = append(overrides, filepath.Join(dir, name))
+		} else {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+
+	return append(files, overrides...)
+}
+
+// isOverrideFile tells whether a configuration file overrides the others
+// ("override.tf" or "*_override.tf", and their JSON counterparts).
+func isOverrideFile(fileName string) bool {
+	base := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(fileName), ".json"), ".tf")
+	return base == "override" || strings.HasSuffix(base, "_override")
+}
+
+// isLocalModuleSource tells whether a module source refers to a local directory.
+func isLocalModuleSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// combineConstraints returns a single constraint expression that is
+// satisfied only by the versions satisfying all the given constraints.
+func combineConstraints(constraints []VersionConstraint) string {
+	exprs := make([]string, 0, len(constraints))
+	for _, vc := range constraints {
+		exprs = append(exprs, vc.Constraint)
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// ConflictError is returned when no version satisfies all the
+// required_version settings of a Terraform configuration.
+type ConflictError struct {
+	Constraints []VersionConstraint
+	Conflicts   [][2]VersionConstraint
+}
+
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("no version satisfies all Terraform version constraints:")
+	for _, vc := range e.Constraints {
+		b.WriteString("\n  " + vc.String())
+	}
+	for _, pair := range e.Conflicts {
+		b.WriteString("\nconflict between " + pair[0].String() + " and " + pair[1].String())
+	}
+
+	return b.String()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrNoMatchingVersion
+}
+
+// newConflictError finds the pairs of constraints that no candidate
+// version satisfies at the same time.
+func newConflictError(constraints []VersionConstraint, candidates []*version.Version) error {
+	parsed := make([]version.Constraints, len(constraints))
+	for i, vc := range constraints {
+		c, err := version.NewConstraint(vc.Constraint)
+		if err != nil {
+			return fmt.Errorf("invalid Terraform version constraint %s: %w", vc, err)
+		}
+		parsed[i] = c
+	}
+
+	e := &ConflictError{Constraints: constraints}
+
+	for i := range constraints {
+		for j := i + 1; j < len(constraints); j++ {
+			compatible := false
+			for _, v := range candidates {
+				if parsed[i].Check(v) && parsed[j].Check(v) {
+					compatible = true
+					break
+				}
+			}
+			if !compatible {
+				e.Conflicts = append(e.Conflicts, [2]VersionConstraint{constraints[i], constraints[j]})
+			}
+		}
+	}
+
+	return e
 }
 
 // ResolveVersion resolves a constraint string to a specific version, checking
-// against the provided cached versions. Returns nil, nil if constraintStr is empty.
-func ResolveVersion(constraintStr string, cachedVersions []*version.Version) (*version.Version, error) {
+// against the provided candidate versions. Returns nil, nil if constraintStr is empty.
+func ResolveVersion(constraintStr string, candidates []*version.Version) (*version.Version, error) {
 	if constraintStr == "" {
 		return nil, nil
 	}
@@ -63,9 +272,9 @@ func ResolveVersion(constraintStr string, cachedVersions []*version.Version) (*v
 		return nil, err
 	}
 
-	// Find the best matching version from the cache.
+	// Find the best matching version.
 	var bestMatch *version.Version
-	for _, v := range cachedVersions {
+	for _, v := range candidates {
 		if constraint.Check(v) {
 			if bestMatch == nil || v.GreaterThan(bestMatch) {
 				bestMatch = v
@@ -78,5 +287,162 @@ func ResolveVersion(constraintStr string, cachedVersions []*version.Version) (*v
 		return bestMatch, nil
 	}
 
-	return nil, fmt.Errorf("no cached version satisfies constraint %q; run 'tfs <version>' to install the version you need", constraintStr)
+	return nil, fmt.Errorf("%w %q", ErrNoMatchingVersion, constraintStr)
+}
+
+// Resolve resolves a constraint string to a specific version, looking for
+// candidates in the cache and in the release source according to the
+// "version_resolution" strategy. Returns nil, nil if constraintStr is empty.
+//
+// The "latest" and "latest:<regex>" expressions (tfenv syntax) select the most
+// recent published release, optionally among the versions matching the regex.
+func (c *LocalCache) Resolve(constraintStr string) (*version.Version, error) {
+	return c.resolve(constraintStr, viper.GetString("version_resolution"))
+}
+
+// resolve resolves a constraint string using the given strategy.
+func (c *LocalCache) resolve(constraintStr, strategy string) (*version.Version, error) {
+	if constraintStr == "" {
+		return nil, nil
+	}
+
+	logger := slog.With("constraint", constraintStr, "strategy", strategy)
+
+	if constraintStr == "latest" || strings.HasPrefix(constraintStr, "latest:") {
+		candidates := c.localVersions()
+		if strategy != ResolveCachedOnly {
+			remote, err := c.RemoteVersions()
+			if err != nil {
+				logger.Warn("Falling back to cached versions", "error", err)
+			}
+			candidates = append(candidates, remote...)
+		}
+		return ResolveLatest(constraintStr, candidates)
+	}
+
+	switch strategy {
+	case ResolveCachedOnly:
+		v, err := ResolveVersion(constraintStr, c.localVersions())
+		if errors.Is(err, ErrNoMatchingVersion) {
+			return nil, fmt.Errorf("%w %q in cache; run 'tfs <version>' to install the version you need", ErrNoMatchingVersion, constraintStr)
+		}
+		return v, err
+
+	case "", ResolveCachedFirst:
+		v, err := ResolveVersion(constraintStr, c.CachedVersions())
+		if !errors.Is(err, ErrNoMatchingVersion) {
+			return v, err
+		}
+		logger.Info("No cached version satisfies constraint, looking for published releases")
+
+		remote, err := c.RemoteVersions()
+		if err != nil {
+			return nil, err
+		}
+		return ResolveVersion(constraintStr, remote)
+
+	case ResolveRemoteFirst:
+		remote, err := c.RemoteVersions()
+		if err != nil {
+			logger.Warn("Falling back to cached versions", "error", err)
+		}
+		return ResolveVersion(constraintStr, append(remote, c.CachedVersions()...))
+
+	default:
+		err := fmt.Errorf("unknown version resolution strategy %q", strategy)
+		logger.Error("Invalid configuration", "error", err)
+		return nil, err
+	}
+}
+
+// localVersions returns the cached versions and, in offline mode, the
+// versions available in the offline source directory, as they can be
+// installed without network access.
+func (c *LocalCache) localVersions() []*version.Version {
+	versions := c.CachedVersions()
+	if !viper.GetBool("offline") {
+		return versions
+	}
+
+	offline, err := c.RemoteVersions()
+	if err != nil {
+		slog.Warn("Falling back to cached versions", "error", err)
+	}
+
+	return append(versions, offline...)
+}
+
+// ResolveRequirement resolves a project version requirement like Resolve
+// does. When the requirement combines several required_version settings
+// that no version satisfies, the error tells which of them conflict.
+func (c *LocalCache) ResolveRequirement(req *Requirement) (*version.Version, error) {
+	return c.resolveRequirement(req, viper.GetString("version_resolution"))
+}
+
+// resolveRequirement resolves a project version requirement using the given strategy.
+func (c *LocalCache) resolveRequirement(req *Requirement, strategy string) (*version.Version, error) {
+	v, err := c.resolve(req.Expression, strategy)
+	if !errors.Is(err, ErrNoMatchingVersion) || len(req.Constraints) < 2 {
+		return v, err
+	}
+
+	candidates := c.localVersions()
+	if strategy != ResolveCachedOnly {
+		if remote, err := c.RemoteVersions(); err == nil {
+			candidates = append(c.CachedVersions(), remote...)
+		}
+	}
+
+	return nil, newConflictError(req.Constraints, candidates)
+}
+
+// ResolveLatest returns the most recent of the candidate versions matching
+// a "latest" or "latest:<regex>" expression. Pre-releases are only selected
+// when a regex is given.
+func ResolveLatest(expr string, candidates []*version.Version) (*version.Version, error) {
+	var pattern *regexp.Regexp
+
+	if raw, ok := strings.CutPrefix(expr, "latest:"); ok {
+		var err error
+		if pattern, err = regexp.Compile(raw); err != nil {
+			slog.Error("Failed to parse version regex", "error", err, "expression", expr)
+			return nil, err
+		}
+	}
+
+	var bestMatch *version.Version
+	for _, v := range candidates {
+		if pattern == nil && v.Prerelease() != "" {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(v.String()) {
+			continue
+		}
+		if bestMatch == nil || v.GreaterThan(bestMatch) {
+			bestMatch = v
+		}
+	}
+
+	if bestMatch == nil {
+		return nil, fmt.Errorf("%w %q", ErrNoMatchingVersion, expr)
+	}
+
+	slog.Info("Resolved version constraint", "constraint", expr, "version", bestMatch.String())
+	return bestMatch, nil
+}
+
+// RemoteVersions returns the versions published by the release source.
+func (c *LocalCache) RemoteVersions() ([]*version.Version, error) {
+	source, err := c.Source()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := source.Versions(context.Background())
+	if err != nil {
+		slog.Error("Failed to list published Terraform versions", "error", err, "source", source.Name())
+		return nil, err
+	}
+
+	return versions, nil
 }
@@ -1,9 +1,15 @@
 package tfs
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-version"
+	"github.com/spf13/viper"
 )
 
 func mustVersion(t *testing.T, s string) *version.Version {
@@ -131,3 +137,282 @@ func TestResolveVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalCacheResolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    string
+		constraint  string
+		offline     bool
+		expectedVer string
+		shouldError bool
+	}{
+		{
+			name:        "Cached first prefers cache",
+			strategy:    ResolveCachedFirst,
+			constraint:  "~> 1.5",
+			expectedVer: "1.5.2",
+		},
+		{
+			name:        "Cached first falls back to remote",
+			strategy:    ResolveCachedFirst,
+			constraint:  "~> 1.6.0",
+			expectedVer: "1.6.3",
+		},
+		{
+			name:        "Default strategy is cached first",
+			strategy:    "",
+			constraint:  "~> 1.6.0",
+			expectedVer: "1.6.3",
+		},
+		{
+			name:        "Remote first prefers highest published release",
+			strategy:    ResolveRemoteFirst,
+			constraint:  "~> 1.5",
+			expectedVer: "1.6.3",
+		},
+		{
+			name:        "Cached only never checks remote",
+			strategy:    ResolveCachedOnly,
+			constraint:  "~> 1.6.0",
+			shouldError: true,
+		},
+		{
+			name:        "No published release matches",
+			strategy:    ResolveCachedFirst,
+			constraint:  "~> 2.0",
+			shouldError: true,
+		},
+		{
+			name:        "Latest published release",
+			strategy:    ResolveCachedFirst,
+			constraint:  "latest",
+			expectedVer: "1.6.3",
+		},
+		{
+			name:        "Latest cached release",
+			strategy:    ResolveCachedOnly,
+			constraint:  "latest",
+			expectedVer: "1.5.2",
+		},
+		{
+			name:        "Cached only uses offline archives",
+			strategy:    ResolveCachedOnly,
+			constraint:  "~> 1.6.0",
+			offline:     true,
+			expectedVer: "1.6.3",
+		},
+		{
+			name:        "Latest offline archive",
+			strategy:    ResolveCachedOnly,
+			constraint:  "latest",
+			offline:     true,
+			expectedVer: "1.6.3",
+		},
+		{
+			name:        "Unknown strategy",
+			strategy:    "random",
+			constraint:  "~> 1.5",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir, cleanup := initTestFS(t)
+			defer cleanup()
+
+			writeTestFile(t, filepath.Join(cacheDir, testFilePrefix+"1.5.2"), []byte("dummy content"))
+			viper.Set("version_resolution", tt.strategy)
+			viper.Set("offline", tt.offline)
+
+			cache := NewLocalCache(cacheDir)
+			cache.SetSource(newFakeSource("1.5.2", "1.6.0", "1.6.3", "1.7.0-beta1"))
+			if err := cache.Load(); err != nil {
+				t.Fatalf("Cache.Load() failed: %v", err)
+			}
+
+			result, err := cache.Resolve(tt.constraint)
+
+			if tt.shouldError {
+				if err == nil {
+					t.Fatalf("expected error, got result %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.String() != tt.expectedVer {
+				t.Fatalf("expected %s, got %s", tt.expectedVer, result.String())
+			}
+		})
+	}
+}
+
+func TestLocalCacheResolveRequirement(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	source := newFakeSource("1.4.6", "1.5.7", "1.6.3")
+	cache.SetSource(source)
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	constraints := []VersionConstraint{
+		{Constraint: ">= 1.4", File: "versions.tf", Line: 2},
+		{Constraint: "~> 1.5.0", File: "modules/a/versions.tf", Line: 3},
+		{Constraint: "~> 1.6.0", File: "modules/b/versions.tf", Line: 4},
+	}
+
+	t.Run("compatible constraints", func(t *testing.T) {
+		req := &Requirement{Expression: combineConstraints(constraints[:2]), Constraints: constraints[:2]}
+
+		v, err := cache.ResolveRequirement(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v.String() != "1.5.7" {
+			t.Fatalf("expected 1.5.7, got %s", v)
+		}
+	})
+
+	t.Run("conflicting constraints", func(t *testing.T) {
+		req := &Requirement{Expression: combineConstraints(constraints), Constraints: constraints}
+
+		_, err := cache.ResolveRequirement(req)
+
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) {
+			t.Fatalf("expected a conflict error, got %v", err)
+		}
+		if !errors.Is(err, ErrNoMatchingVersion) {
+			t.Fatalf("expected error to wrap ErrNoMatchingVersion")
+		}
+		expected := [][2]VersionConstraint{{constraints[1], constraints[2]}}
+		if !reflect.DeepEqual(conflict.Conflicts, expected) {
+			t.Fatalf("expected conflicts %+v, got %+v", expected, conflict.Conflicts)
+		}
+		if !strings.Contains(err.Error(), "modules/b/versions.tf:4") {
+			t.Fatalf("expected error to locate the constraints, got %q", err)
+		}
+	})
+
+	t.Run("conflicting constraints with cached only resolution", func(t *testing.T) {
+		viper.Set("version_resolution", ResolveCachedOnly)
+		defer viper.Set("version_resolution", "")
+
+		source.listed = 0
+		req := &Requirement{Expression: combineConstraints(constraints), Constraints: constraints}
+
+		var conflict *ConflictError
+		if _, err := cache.ResolveRequirement(req); !errors.As(err, &conflict) {
+			t.Fatalf("expected a conflict error, got %v", err)
+		}
+		if source.listed != 0 {
+			t.Fatalf("expected the release source not to be queried, got %d calls", source.listed)
+		}
+	})
+}
+
+func TestResolveLatest(t *testing.T) {
+	candidates := []string{"1.4.6", "1.5.0-beta1", "1.5.0", "1.5.7", "1.6.0-alpha1"}
+
+	tests := []struct {
+		name        string
+		expr        string
+		expectedVer string
+		shouldError bool
+	}{
+		{
+			name:        "Latest stable version",
+			expr:        "latest",
+			expectedVer: "1.5.7",
+		},
+		{
+			name:        "Latest version matching regex",
+			expr:        "latest:^1.4",
+			expectedVer: "1.4.6",
+		},
+		{
+			name:        "Pre-release matching regex",
+			expr:        "latest:^1.6",
+			expectedVer: "1.6.0-alpha1",
+		},
+		{
+			name:        "No version matching regex",
+			expr:        "latest:^2",
+			shouldError: true,
+		},
+		{
+			name:        "Invalid regex",
+			expr:        "latest:(",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ResolveLatest(tt.expr, makeVersions(t, candidates))
+
+			if tt.shouldError {
+				if err == nil {
+					t.Fatalf("expected error for expression %q, got result %v", tt.expr, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.String() != tt.expectedVer {
+				t.Fatalf("expected %s, got %s", tt.expectedVer, result.String())
+			}
+		})
+	}
+}
+
+func TestModuleVersionConstraintsOverride(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"network", "network-v2"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("Failed to create module: %v", err)
+		}
+	}
+
+	writeProjectFile(t, dir, "main.tf", `
+terraform {
+  required_version = ">= 1.3"
+}
+
+module "network" {
+  source = "./network"
+}
+`)
+	writeProjectFile(t, dir, "versions.tf", `terraform { required_version = "< 2.0" }`)
+	writeProjectFile(t, dir, "z_override.tf", `
+module "network" {
+  source = "./network-v2"
+}
+
+terraform {
+  required_version = "~> 1.6"
+}
+`)
+	writeProjectFile(t, filepath.Join(dir, "network"), "main.tf", `terraform { required_version = "1.0.0" }`)
+	writeProjectFile(t, filepath.Join(dir, "network-v2"), "main.tf", `terraform { required_version = ">= 1.5" }`)
+
+	constraints, err := moduleVersionConstraints(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []VersionConstraint{
+		{Constraint: "~> 1.6", File: filepath.Join(dir, "z_override.tf"), Line: 7},
+		{Constraint: ">= 1.5", File: filepath.Join(dir, "network-v2", "main.tf"), Line: 1},
+	}
+	if !reflect.DeepEqual(constraints, expected) {
+		t.Fatalf("expected constraints %+v, got %+v", expected, constraints)
+	}
+}
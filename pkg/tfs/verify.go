@@ -0,0 +1,337 @@
+package tfs
+
+import (
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/viper"
+)
+
+// Name of the directory where corrupted releases are moved to.
+const quarantineDirName = "quarantine"
+
+// VerifyOptions holds the settings of a cache integrity check.
+type VerifyOptions struct {
+	// Directory holding a local copy of the SHA256SUMS files,
+	// used instead of the release source when not empty.
+	ChecksumsDirectory string
+
+	// Run each binary to make sure it reports the expected version.
+	Exec bool
+
+	// Move corrupted releases out of the cache.
+	Quarantine bool
+}
+
+// verifyResult holds the outcome of a release integrity check.
+type verifyResult struct {
+	release  *release
+	problems []string
+	warnings []string
+}
+
+func (res *verifyResult) fail(format string, a ...any) {
+	res.problems = append(res.problems, fmt.Sprintf(format, a...))
+}
+
+func (res *verifyResult) warn(format string, a ...any) {
+	res.warnings = append(res.warnings, fmt.Sprintf(format, a...))
+}
+
+// Release integrity statuses.
+const (
+	VerifyStatusOK          = "ok"
+	VerifyStatusUnverified  = "unverified"
+	VerifyStatusCorrupted   = "corrupted"
+	VerifyStatusQuarantined = "quarantined"
+)
+
+// VerifyResult is the outcome of the integrity check of a release.
+type VerifyResult struct {
+	Version  string   `json:"version"`
+	Status   string   `json:"status"`
+	Problems []string `json:"problems,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// VerifyReport is the report of the "verify" command.
+type VerifyReport []VerifyResult
+
+// WriteText writes one release per line, with the problems found.
+func (v VerifyReport) WriteText(w io.Writer) error {
+	for _, res := range v {
+		var err error
+		switch res.Status {
+		case VerifyStatusOK:
+			_, err = color.New(color.FgGreen).Fprintln(w, res.Version+" ok")
+		case VerifyStatusUnverified:
+			_, err = color.New(color.FgYellow).Fprintln(w, res.Version+" "+res.Status+": "+strings.Join(res.Warnings, "; "))
+		default:
+			_, err = color.New(color.FgRed, color.Bold).Fprintln(w, res.Version+" "+res.Status+": "+strings.Join(res.Problems, "; "))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v VerifyReport) tableRows() [][]string {
+	rows := [][]string{{"VERSION", "STATUS", "DETAILS"}}
+	for _, res := range v {
+		rows = append(rows, []string{res.Version, res.Status, strings.Join(slices.Concat(res.Problems, res.Warnings), "; ")})
+	}
+	return rows
+}
+
+// Verify checks the integrity of every cached release. The report is
+// returned along with an error if any of them looks corrupted or
+// tampered with.
+func (c *LocalCache) Verify(opts VerifyOptions) (VerifyReport, error) {
+	var source Source
+
+	if opts.ChecksumsDirectory != "" {
+		source = &directorySource{directory: opts.ChecksumsDirectory}
+	} else {
+		var err error
+		if source, err = c.Source(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Quarantine {
+		unlock, err := c.Lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	versions := c.CachedVersions()
+	sort.Sort(version.Collection(versions))
+
+	var (
+		report    = make(VerifyReport, 0, len(versions))
+		corrupted int
+	)
+
+	for _, v := range versions {
+		r := c.releases[v.String()]
+		res := r.verify(source, opts.Exec)
+
+		if len(res.problems) > 0 {
+			corrupted++
+			if opts.Quarantine {
+				if err := r.quarantine(); err != nil {
+					return report, err
+				}
+			}
+		}
+		report = append(report, res.report(opts.Quarantine))
+	}
+
+	if corrupted > 0 {
+		return report, fmt.Errorf("%d corrupted release(s) found in cache", corrupted)
+	}
+
+	return report, nil
+}
+
+// verify runs all integrity checks on the release.
+func (r *release) verify(source Source, run bool) *verifyResult {
+	res := &verifyResult{release: r}
+	target := filepath.Join(r.parentCache.directory, r.fileName)
+
+	// Binary checksum, compared with the one recorded at install time.
+	if err := r.VerifyChecksum(); err != nil {
+		res.fail("%v", err)
+	}
+
+	sums, err := readChecksumFile(r.checksumPath())
+	if err != nil {
+		res.fail("unreadable checksum file: %v", err)
+	}
+	if _, ok := sums[r.fileName]; !ok {
+		res.warn("no checksum recorded at install time")
+	}
+
+	// Archive checksum, compared with the official SHA256SUMS file.
+	if recorded, ok := sums[archiveName(r.Version)]; ok {
+		official, err := source.Checksums(context.Background(), r.Version)
+		switch {
+		case err != nil:
+			res.warn("official checksums unavailable: %v", err)
+		case official[archiveName(r.Version)] == "":
+			res.fail("no official checksum for %s", archiveName(r.Version))
+		case official[archiveName(r.Version)] != recorded:
+			res.fail("recorded archive checksum does not match official SHA256SUMS")
+		}
+	} else {
+		res.warn("no archive checksum recorded at install time")
+	}
+
+	// Executable format.
+	if err := checkPlatform(target); errors.Is(err, errUnknownPlatform) {
+		res.warn("executable format check skipped: %v", err)
+	} else if err != nil {
+		res.fail("%v", err)
+	}
+
+	// Reported version.
+	if run && len(res.problems) == 0 {
+		if reported, err := terraformVersion(target); err != nil {
+			res.fail("failed to run binary: %v", err)
+		} else if !reported.Equal(r.Version) {
+			res.fail("binary reports version %s", reported)
+		}
+	}
+
+	return res
+}
+
+// quarantine moves the release files out of the cache.
+func (r *release) quarantine() error {
+	var (
+		quarantineDir = filepath.Join(r.parentCache.directory, quarantineDirName)
+		target        = filepath.Join(r.parentCache.directory, r.fileName)
+	)
+
+	logger := slog.With(
+		"version", r.Version.String(),
+		"fileName", target,
+		"quarantineDirectory", quarantineDir,
+	)
+
+	// Make sure the symlink does not point to a missing file.
+	symlink := filepath.Join(viper.GetString("user_bin_directory"), "terraform")
+	if path, ok, _ := AppFs.EvalSymlinksIfPossible(symlink); ok && path == target {
+		AppFs.Remove(symlink)
+	}
+
+	if err := AppFs.MkdirAll(quarantineDir, os.ModePerm); err != nil {
+		logger.Error("Failed to create quarantine directory", "error", err)
+		return err
+	}
+
+	for _, path := range []string{target, r.checksumPath()} {
+		err := AppFs.Rename(path, filepath.Join(quarantineDir, filepath.Base(path)))
+		if err != nil && !os.IsNotExist(err) {
+			logger.Error("Failed to quarantine Terraform binary", "error", err)
+			return err
+		}
+	}
+
+	logger.Warn("Moved corrupted Terraform binary to quarantine")
+
+	delete(r.parentCache.releases, r.Version.String())
+	r.forget()
+
+	return nil
+}
+
+// report returns the outcome of the integrity check.
+func (res *verifyResult) report(quarantined bool) VerifyResult {
+	status := VerifyStatusOK
+
+	switch {
+	case len(res.problems) > 0 && quarantined:
+		status = VerifyStatusQuarantined
+	case len(res.problems) > 0:
+		status = VerifyStatusCorrupted
+	case len(res.warnings) > 0:
+		status = VerifyStatusUnverified
+	}
+
+	return VerifyResult{
+		Version:  res.release.Version.String(),
+		Status:   status,
+		Problems: res.problems,
+		Warnings: res.warnings,
+	}
+}
+
+// errUnknownPlatform is returned when the executable format
+// of the current platform cannot be checked.
+var errUnknownPlatform = errors.New("unknown platform")
+
+// checkPlatform makes sure the file is an executable for the current platform.
+func checkPlatform(path string) error {
+	return checkExecutable(path, runtime.GOOS, runtime.GOARCH)
+}
+
+// checkExecutable makes sure the file is an executable for the given platform.
+func checkExecutable(path, goos, goarch string) error {
+	f, err := AppFs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch goos {
+	case "darwin":
+		expected, ok := map[string]macho.Cpu{"amd64": macho.CpuAmd64, "arm64": macho.CpuArm64}[goarch]
+		if !ok {
+			return fmt.Errorf("%w %s/%s", errUnknownPlatform, goos, goarch)
+		}
+		mf, err := macho.NewFile(f)
+		if err != nil {
+			return fmt.Errorf("not a Mach-O executable: %w", err)
+		}
+		if mf.Cpu != expected {
+			return fmt.Errorf("executable built for %s, expected %s", mf.Cpu, expected)
+		}
+	case "windows":
+		// Not checked.
+	default:
+		expected, ok := map[string]elf.Machine{
+			"386":   elf.EM_386,
+			"amd64": elf.EM_X86_64,
+			"arm":   elf.EM_ARM,
+			"arm64": elf.EM_AARCH64,
+		}[goarch]
+		if !ok {
+			return fmt.Errorf("%w %s/%s", errUnknownPlatform, goos, goarch)
+		}
+		ef, err := elf.NewFile(f)
+		if err != nil {
+			return fmt.Errorf("not an ELF executable: %w", err)
+		}
+		if ef.Machine != expected {
+			return fmt.Errorf("executable built for %s, expected %s", ef.Machine, expected)
+		}
+	}
+
+	return nil
+}
+
+// terraformVersion runs the binary to get the version it reports.
+func terraformVersion(path string) (*version.Version, error) {
+	out, err := exec.Command(path, "version", "-json").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, err
+	}
+
+	return version.NewVersion(info.TerraformVersion)
+}
@@ -0,0 +1,101 @@
+package tfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// newExecutableSource returns a release source whose binaries are
+// real executables for the current platform (the test binary itself).
+func newExecutableSource(t *testing.T, versions ...string) *fakeSource {
+	t.Helper()
+	executable, err := os.ReadFile(os.Args[0])
+	if err != nil {
+		t.Fatalf("Failed to read test executable: %v", err)
+	}
+	s := newFakeSource(versions...)
+	for _, v := range versions {
+		s.binaries[v] = executable
+	}
+	return s
+}
+
+func TestCacheVerify(t *testing.T) {
+	cacheDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	cache := NewLocalCache(cacheDir)
+	cache.SetSource(newExecutableSource(t, "1.9.0", "1.10.0"))
+
+	for _, v := range []string{"1.9.0", "1.10.0"} {
+		if err := cache.NewRelease(mustVersion(t, v)).Install(); err != nil {
+			t.Fatalf("Install() failed: %v", err)
+		}
+	}
+	if err := cache.Load(); err != nil {
+		t.Fatalf("Cache.Load() failed: %v", err)
+	}
+
+	t.Run("healthy cache", func(t *testing.T) {
+		report, err := cache.Verify(VerifyOptions{})
+		if err != nil {
+			t.Fatalf("Cache.Verify() failed: %v", err)
+		}
+		if len(report) != 2 || report[0].Version != "1.9.0" || report[0].Status == VerifyStatusCorrupted {
+			t.Errorf("Unexpected report %+v", report)
+		}
+	})
+
+	t.Run("tampered binary", func(t *testing.T) {
+		target := filepath.Join(cacheDir, testFilePrefix+"1.9.0")
+		writeTestFile(t, target, []byte("malicious content"))
+
+		report, err := cache.Verify(VerifyOptions{Quarantine: true})
+		if err == nil {
+			t.Fatalf("Expected Cache.Verify() to fail")
+		}
+		if len(report) != 2 || report[0].Status != VerifyStatusQuarantined || len(report[0].Problems) == 0 {
+			t.Errorf("Expected 1.9.0 to be reported as quarantined, got %+v", report)
+		}
+
+		if exists, _ := afero.Exists(AppFs, target); exists {
+			t.Errorf("Expected %s to be moved out of the cache", target)
+		}
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, quarantineDirName, testFilePrefix+"1.9.0")); !exists {
+			t.Errorf("Expected 1.9.0 to be quarantined")
+		}
+		if exists, _ := afero.Exists(AppFs, filepath.Join(cacheDir, testFilePrefix+"1.10.0")); !exists {
+			t.Errorf("Expected 1.10.0 to remain")
+		}
+	})
+}
+
+func TestCheckPlatform(t *testing.T) {
+	tempDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	path := filepath.Join(tempDir, "not-an-executable")
+	writeTestFile(t, path, []byte("#!/bin/sh"))
+
+	if err := checkPlatform(path); err == nil {
+		t.Errorf("Expected error for a file that is not an executable")
+	}
+}
+
+func TestCheckExecutableUnknownPlatform(t *testing.T) {
+	tempDir, cleanup := initTestFS(t)
+	defer cleanup()
+
+	path := filepath.Join(tempDir, "terraform")
+	writeTestFile(t, path, []byte("#!/bin/sh"))
+
+	for _, goos := range []string{"linux", "darwin"} {
+		if err := checkExecutable(path, goos, "riscv64"); !errors.Is(err, errUnknownPlatform) {
+			t.Errorf("Expected errUnknownPlatform on %s/riscv64, got %v", goos, err)
+		}
+	}
+}
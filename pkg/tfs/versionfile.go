@@ -0,0 +1,210 @@
+package tfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Version requirement sources.
+const (
+	SourceEnvironment          = "TFS_TERRAFORM_VERSION"
+	SourceTerraformVersionFile = ".terraform-version"
+	SourceToolVersionsFile     = ".tool-versions"
+	SourceRequiredVersion      = "required_version"
+)
+
+// Requirement is a Terraform version requirement found in a project.
+type Requirement struct {
+	// Version, constraint, or "latest[:<regex>]" expression.
+	Expression string
+
+	// Kind of source the requirement comes from.
+	Source string
+
+	// Path of the file the requirement was read from, if any.
+	File string
+
+	// Project directory the requirement was found in.
+	Directory string
+
+	// required_version settings the expression was built from, if any.
+	Constraints []VersionConstraint
+}
+
+// FindVersionRequirement looks for the Terraform version required in the
+// current directory and its parents, up to the repository root (the first
+// directory holding a .git entry) or the "version_search_boundary" setting.
+// The nearest directory holding a requirement wins, and within a directory
+// sources are checked in the following order:
+//
+//  1. .terraform-version file (tfenv)
+//  2. .tool-versions file (asdf)
+//  3. required_version settings in Terraform configuration, including
+//     those of the local modules it calls
+//
+// The TFS_TERRAFORM_VERSION environment variable takes precedence over all
+// of them. Returns nil, nil if no requirement was found.
+func FindVersionRequirement() (*Requirement, error) {
+	path, err := os.Getwd()
+	if err != nil {
+		slog.Error("Failed to get working directory", "error", err)
+		return nil, err
+	}
+
+	if expr := strings.TrimSpace(os.Getenv(SourceEnvironment)); expr != "" {
+		return &Requirement{Expression: expr, Source: SourceEnvironment, Directory: path}, nil
+	}
+
+	for _, dir := range searchDirectories(path) {
+		req, err := findDirectoryRequirement(dir)
+		if req != nil {
+			req.Directory = dir
+		}
+		if err != nil || req != nil {
+			return req, err
+		}
+	}
+
+	return nil, nil
+}
+
+// findDirectoryRequirement looks for a Terraform version requirement in the
+// given directory only. Returns nil, nil if no requirement was found.
+func findDirectoryRequirement(dir string) (*Requirement, error) {
+	for _, read := range []func(string) (*Requirement, error){
+		readTerraformVersionFile,
+		readToolVersionsFile,
+	} {
+		req, err := read(dir)
+		if err != nil || req != nil {
+			return req, err
+		}
+	}
+
+	constraints, err := moduleVersionConstraints(dir)
+	if err != nil || len(constraints) == 0 {
+		return nil, err
+	}
+
+	return &Requirement{
+		Expression:  combineConstraints(constraints),
+		Source:      SourceRequiredVersion,
+		File:        constraints[0].File,
+		Constraints: constraints,
+	}, nil
+}
+
+// searchDirectories returns the directories where version requirements are
+// looked for, from the given path up to the search boundary, the repository
+// root or the user home directory, whichever comes first. The home directory
+// itself is only searched when it is the given path.
+func searchDirectories(path string) []string {
+	boundary := viper.GetString("version_search_boundary")
+	if boundary != "" {
+		boundary = filepath.Clean(boundary)
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		home = filepath.Clean(home)
+	}
+
+	var dirs []string
+
+	for dir := filepath.Clean(path); ; {
+		dirs = append(dirs, dir)
+
+		if dir == boundary || dir == home {
+			break
+		}
+		// Stop at the repository root.
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || parent == home {
+			// Filesystem root, or files of the home directory
+			// that do not belong to any project.
+			break
+		}
+		dir = parent
+	}
+
+	return dirs
+}
+
+// readTerraformVersionFile reads the version from a tfenv .terraform-version
+// file in the given directory. Returns nil, nil if there is no such file.
+func readTerraformVersionFile(dir string) (*Requirement, error) {
+	path := filepath.Join(dir, SourceTerraformVersionFile)
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Failed to read version file", "error", err, "fileName", path)
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return &Requirement{Expression: line, Source: SourceTerraformVersionFile, File: path}, nil
+	}
+
+	return nil, fmt.Errorf("no version found in %s", path)
+}
+
+// readToolVersionsFile reads the Terraform version from an asdf .tool-versions
+// file in the given directory. Returns nil, nil if there is no such file, or
+// if the file does not mention Terraform.
+func readToolVersionsFile(dir string) (*Requirement, error) {
+	path := filepath.Join(dir, SourceToolVersionsFile)
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Failed to read version file", "error", err, "fileName", path)
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != terraformProductName {
+			continue
+		}
+
+		// asdf falls back to the next versions when the first one is not
+		// installed, but we can install any version so the first one wins.
+		expr := fields[1]
+
+		switch {
+		case strings.HasPrefix(expr, "latest:"):
+			// asdf filters versions by prefix.
+			expr = "latest:^" + regexp.QuoteMeta(strings.TrimPrefix(expr, "latest:"))
+		case expr == "system" || strings.HasPrefix(expr, "ref:") || strings.HasPrefix(expr, "path:"):
+			return nil, fmt.Errorf("unsupported Terraform version %q in %s", expr, path)
+		}
+
+		return &Requirement{Expression: expr, Source: SourceToolVersionsFile, File: path}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,317 @@
+package tfs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func writeProjectFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestFindVersionRequirement(t *testing.T) {
+	tests := []struct {
+		name               string
+		files              map[string]string
+		env                string
+		expectedExpression string
+		expectedSource     string
+		shouldBeNil        bool
+		shouldError        bool
+	}{
+		{
+			name:               "Terraform version file",
+			files:              map[string]string{".terraform-version": "# pinned\n1.5.7\n"},
+			expectedExpression: "1.5.7",
+			expectedSource:     SourceTerraformVersionFile,
+		},
+		{
+			name:               "Terraform version file with latest regex",
+			files:              map[string]string{".terraform-version": "latest:^1.5"},
+			expectedExpression: "latest:^1.5",
+			expectedSource:     SourceTerraformVersionFile,
+		},
+		{
+			name:               "Tool versions file",
+			files:              map[string]string{".tool-versions": "nodejs 20.1.0\nterraform 1.6.2 1.5.7 # comment\n"},
+			expectedExpression: "1.6.2",
+			expectedSource:     SourceToolVersionsFile,
+		},
+		{
+			name:               "Tool versions file with latest prefix",
+			files:              map[string]string{".tool-versions": "terraform latest:1.5"},
+			expectedExpression: `latest:^1\.5`,
+			expectedSource:     SourceToolVersionsFile,
+		},
+		{
+			name:        "Tool versions file with system version",
+			files:       map[string]string{".tool-versions": "terraform system"},
+			shouldError: true,
+		},
+		{
+			name:        "Tool versions file without Terraform",
+			files:       map[string]string{".tool-versions": "nodejs 20.1.0"},
+			shouldBeNil: true,
+		},
+		{
+			name: "Required version",
+			files: map[string]string{
+				"main.tf": `terraform { required_version = "~> 1.5" }`,
+			},
+			expectedExpression: "~> 1.5",
+			expectedSource:     SourceRequiredVersion,
+		},
+		{
+			name: "Terraform version file takes precedence",
+			files: map[string]string{
+				".terraform-version": "1.5.7",
+				".tool-versions":     "terraform 1.6.2",
+				"main.tf":            `terraform { required_version = "~> 1.5" }`,
+			},
+			expectedExpression: "1.5.7",
+			expectedSource:     SourceTerraformVersionFile,
+		},
+		{
+			name: "Tool versions file takes precedence over required version",
+			files: map[string]string{
+				".tool-versions": "terraform 1.6.2",
+				"main.tf":        `terraform { required_version = "~> 1.5" }`,
+			},
+			expectedExpression: "1.6.2",
+			expectedSource:     SourceToolVersionsFile,
+		},
+		{
+			name: "Environment variable takes precedence",
+			files: map[string]string{
+				".terraform-version": "1.5.7",
+			},
+			env:                "latest",
+			expectedExpression: "latest",
+			expectedSource:     SourceEnvironment,
+		},
+		{
+			name:        "Empty Terraform version file",
+			files:       map[string]string{".terraform-version": "\n"},
+			shouldError: true,
+		},
+		{
+			name:        "Nothing found",
+			shouldBeNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeProjectFile(t, dir, name, content)
+			}
+			t.Chdir(dir)
+			t.Setenv(SourceEnvironment, tt.env)
+			viper.Set("version_search_boundary", dir)
+			defer viper.Reset()
+
+			req, err := FindVersionRequirement()
+
+			if tt.shouldError {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", req)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.shouldBeNil {
+				if req != nil {
+					t.Fatalf("expected nil, got %+v", req)
+				}
+				return
+			}
+			if req == nil {
+				t.Fatalf("expected %q, got nil", tt.expectedExpression)
+			}
+			if req.Expression != tt.expectedExpression || req.Source != tt.expectedSource {
+				t.Fatalf("expected %q from %s, got %q from %s", tt.expectedExpression, tt.expectedSource, req.Expression, req.Source)
+			}
+		})
+	}
+}
+
+func TestFindVersionRequirementInModules(t *testing.T) {
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	for _, sub := range []string{"modules/network", "modules/unused", "shared"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("Failed to create module: %v", err)
+		}
+	}
+
+	writeProjectFile(t, dir, "main.tf", `
+module "network" {
+  source = "./modules/network"
+}
+
+module "registry" {
+  source = "hashicorp/consul/aws"
+}
+`)
+	writeProjectFile(t, dir, "versions.tf", `
+terraform {
+  required_version = ">= 1.3"
+}
+`)
+	writeProjectFile(t, filepath.Join(dir, "modules", "network"), "main.tf.json",
+		`{"terraform": {"required_version": "< 1.7"}, "module": {"shared": {"source": "../../shared"}}}`)
+	writeProjectFile(t, filepath.Join(dir, "modules", "unused"), "main.tf", `terraform { required_version = "1.0.0" }`)
+	writeProjectFile(t, filepath.Join(dir, "shared"), "main.tf", `
+module "root" {
+  source = "../"
+}
+
+terraform {
+  required_version = "~> 1.5"
+}
+`)
+
+	t.Chdir(dir)
+	t.Setenv(SourceEnvironment, "")
+	viper.Set("version_search_boundary", dir)
+
+	req, err := FindVersionRequirement()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req == nil {
+		t.Fatal("expected a requirement, got nil")
+	}
+
+	expected := []VersionConstraint{
+		{Constraint: ">= 1.3", File: filepath.Join(dir, "versions.tf"), Line: 3},
+		{Constraint: "< 1.7", File: filepath.Join(dir, "modules", "network", "main.tf.json"), Line: 1},
+		{Constraint: "~> 1.5", File: filepath.Join(dir, "shared", "main.tf"), Line: 7},
+	}
+	if !reflect.DeepEqual(req.Constraints, expected) {
+		t.Fatalf("expected constraints %+v, got %+v", expected, req.Constraints)
+	}
+	if req.Expression != ">= 1.3, < 1.7, ~> 1.5" {
+		t.Fatalf("unexpected combined expression %q", req.Expression)
+	}
+	if req.File != filepath.Join(dir, "versions.tf") {
+		t.Fatalf("unexpected file %q", req.File)
+	}
+}
+
+func TestFindVersionRequirementInParents(t *testing.T) {
+	defer viper.Reset()
+
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	module := filepath.Join(repo, "modules", "network")
+
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if err := os.MkdirAll(module, 0755); err != nil {
+		t.Fatalf("Failed to create module: %v", err)
+	}
+	// Outside of the repository, must never be used.
+	writeProjectFile(t, root, ".terraform-version", "1.0.0")
+
+	t.Chdir(module)
+	t.Setenv(SourceEnvironment, "")
+
+	t.Run("stops at repository root", func(t *testing.T) {
+		req, err := FindVersionRequirement()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if req != nil {
+			t.Fatalf("expected nil, got %+v", req)
+		}
+	})
+
+	t.Run("found in repository root", func(t *testing.T) {
+		writeProjectFile(t, repo, ".terraform-version", "1.5.7")
+
+		req, err := FindVersionRequirement()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if req == nil || req.Expression != "1.5.7" || req.File != filepath.Join(repo, ".terraform-version") {
+			t.Fatalf("expected 1.5.7 from repository root, got %+v", req)
+		}
+	})
+
+	t.Run("nearest directory wins", func(t *testing.T) {
+		writeProjectFile(t, filepath.Join(repo, "modules"), ".tool-versions", "terraform 1.6.2")
+
+		req, err := FindVersionRequirement()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if req == nil || req.Expression != "1.6.2" {
+			t.Fatalf("expected 1.6.2 from the nearest directory, got %+v", req)
+		}
+	})
+
+	t.Run("stops at configured boundary", func(t *testing.T) {
+		viper.Set("version_search_boundary", module)
+
+		req, err := FindVersionRequirement()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if req != nil {
+			t.Fatalf("expected nil, got %+v", req)
+		}
+	})
+}
+
+func TestFindVersionRequirementStopsAtHome(t *testing.T) {
+	defer viper.Reset()
+
+	home := t.TempDir()
+	project := filepath.Join(home, "src", "infra")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	// Not part of any project, must never be used.
+	writeProjectFile(t, home, ".terraform-version", "1.0.0")
+	writeProjectFile(t, filepath.Dir(home), ".tool-versions", "terraform 1.1.0")
+
+	t.Setenv("HOME", home)
+	t.Setenv(SourceEnvironment, "")
+
+	t.Run("from a project", func(t *testing.T) {
+		t.Chdir(project)
+
+		req, err := FindVersionRequirement()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if req != nil {
+			t.Fatalf("expected nil, got %+v", req)
+		}
+	})
+
+	t.Run("from the home directory", func(t *testing.T) {
+		t.Chdir(home)
+
+		req, err := FindVersionRequirement()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if req == nil || req.Expression != "1.0.0" {
+			t.Fatalf("expected 1.0.0 from the current directory, got %+v", req)
+		}
+	})
+}